@@ -0,0 +1,356 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Alarm starts playback on a cron schedule: transfer to a device, set volume and shuffle, play a context,
+// and optionally ramp the volume up from RampFrom to Volume over RampSeconds.
+type Alarm struct {
+	ID          string       `json:"id"`
+	UserID      string       `json:"user_id"`
+	Name        string       `json:"name"`
+	Schedule    string       `json:"schedule"`
+	Timezone    string       `json:"timezone,omitempty"`
+	Enabled     bool         `json:"enabled"`
+	DeviceID    string       `json:"device_id,omitempty"`
+	DeviceName  string       `json:"device_name,omitempty"`
+	ContextURI  string       `json:"context_uri"`
+	Volume      int          `json:"volume"`
+	Shuffle     bool         `json:"shuffle"`
+	RampFrom    int          `json:"ramp_from,omitempty"`
+	RampSeconds int          `json:"ramp_seconds,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	LastRunAt   *time.Time   `json:"last_run_at,omitempty"`
+	LastResult  []StepResult `json:"last_result,omitempty"`
+	NextRunAt   *time.Time   `json:"next_run_at,omitempty"`
+}
+
+// spec returns the schedule in the form understood by the cron parser.
+func (a *Alarm) spec() string {
+	if a.Timezone == "" {
+		return a.Schedule
+	}
+	return "CRON_TZ=" + a.Timezone + " " + a.Schedule
+}
+
+// Validate checks the schedule, time zone, target device and volume settings.
+func (a *Alarm) Validate() error {
+	if strings.HasPrefix(a.Schedule, "CRON_TZ=") || strings.HasPrefix(a.Schedule, "TZ=") {
+		return fmt.Errorf("set the time zone with the timezone field")
+	}
+	if a.Timezone != "" {
+		if _, err := time.LoadLocation(a.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", a.Timezone)
+		}
+	}
+	if _, err := cron.ParseStandard(a.spec()); err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+	if a.DeviceID == "" && a.DeviceName == "" {
+		return fmt.Errorf("device_id or device_name is required")
+	}
+	if a.ContextURI == "" {
+		return fmt.Errorf("context_uri is required")
+	}
+	if err := ValidateSpotifyURI(a.ContextURI, ContextURITypes...); err != nil {
+		return fmt.Errorf("context_uri: %w", err)
+	}
+	if a.Volume < 0 || a.Volume > 100 || a.RampFrom < 0 || a.RampFrom > 100 {
+		return fmt.Errorf("volumes must be between 0 and 100")
+	}
+	if a.RampSeconds < 0 || a.RampSeconds > 3600 {
+		return fmt.Errorf("ramp_seconds must be between 0 and 3600")
+	}
+	return nil
+}
+
+// AlarmScheduler runs alarms with a cron scheduler and persists them in a JSON file.
+type AlarmScheduler struct {
+	tokenMx *TokenManager
+	devices *DeviceResolver
+	limits  *VolumeLimits
+	fader   *VolumeFader
+	path    string
+	cron    *cron.Cron
+	alarms  map[string]*Alarm
+	entries map[string]cron.EntryID
+	mutx    sync.Mutex
+}
+
+func NewAlarmScheduler(tokenMx *TokenManager, devices *DeviceResolver, limits *VolumeLimits, fader *VolumeFader, path string) (*AlarmScheduler, error) {
+	as := &AlarmScheduler{
+		tokenMx: tokenMx,
+		devices: devices,
+		limits:  limits,
+		fader:   fader,
+		path:    path,
+		cron:    cron.New(),
+		alarms:  map[string]*Alarm{},
+		entries: map[string]cron.EntryID{},
+	}
+	var alarms []*Alarm
+	if err := loadJSON(path, &alarms); err != nil {
+		return nil, err
+	}
+	for _, alarm := range alarms {
+		as.alarms[alarm.ID] = alarm
+		if err := as.schedule(alarm); err != nil {
+			return nil, fmt.Errorf("alarm %s: %w", alarm.ID, err)
+		}
+	}
+	return as, nil
+}
+
+// Start runs the cron scheduler in the background until ctx is cancelled.
+func (as *AlarmScheduler) Start(ctx context.Context) {
+	as.cron.Start()
+	go func() {
+		<-ctx.Done()
+		as.cron.Stop()
+	}()
+}
+
+// schedule (re)registers an alarm with the cron scheduler. Callers hold as.mutx or own as exclusively.
+func (as *AlarmScheduler) schedule(alarm *Alarm) error {
+	as.unschedule(alarm.ID)
+	if !alarm.Enabled {
+		return nil
+	}
+	id := alarm.ID
+	entry, err := as.cron.AddFunc(alarm.spec(), func() { as.fire(id) })
+	if err != nil {
+		return err
+	}
+	as.entries[alarm.ID] = entry
+	return nil
+}
+
+// unschedule removes an alarm from the cron scheduler. Callers hold as.mutx.
+func (as *AlarmScheduler) unschedule(id string) {
+	if entry, ok := as.entries[id]; ok {
+		as.cron.Remove(entry)
+		delete(as.entries, id)
+	}
+}
+
+// Create validates and schedules a new alarm. The status tells an invalid alarm (400) from a storage failure (500).
+func (as *AlarmScheduler) Create(alarm *Alarm) (int, error) {
+	if err := alarm.Validate(); err != nil {
+		return http.StatusBadRequest, err
+	}
+	as.mutx.Lock()
+	defer as.mutx.Unlock()
+
+	alarm.ID = newID()
+	alarm.CreatedAt = time.Now().UTC()
+	as.alarms[alarm.ID] = alarm
+	if err := as.schedule(alarm); err != nil {
+		delete(as.alarms, alarm.ID)
+		return http.StatusBadRequest, err
+	}
+	if err := as.save(); err != nil {
+		as.unschedule(alarm.ID)
+		delete(as.alarms, alarm.ID)
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusCreated, nil
+}
+
+// Update replaces the settings of an existing alarm, keeping its identity and run history.
+// Like Create, the status tells an invalid alarm from a storage failure.
+func (as *AlarmScheduler) Update(userID, id string, alarm *Alarm) (bool, int, error) {
+	if err := alarm.Validate(); err != nil {
+		return true, http.StatusBadRequest, err
+	}
+	as.mutx.Lock()
+	defer as.mutx.Unlock()
+
+	existing, ok := as.alarms[id]
+	if !ok || existing.UserID != userID {
+		return false, http.StatusNotFound, nil
+	}
+	alarm.ID, alarm.UserID, alarm.CreatedAt = existing.ID, existing.UserID, existing.CreatedAt
+	alarm.LastRunAt, alarm.LastResult = existing.LastRunAt, existing.LastResult
+	as.alarms[id] = alarm
+	if err := as.schedule(alarm); err != nil {
+		as.alarms[id] = existing
+		as.schedule(existing)
+		return true, http.StatusBadRequest, err
+	}
+	if err := as.save(); err != nil {
+		as.alarms[id] = existing
+		as.schedule(existing)
+		return true, http.StatusInternalServerError, err
+	}
+	return true, http.StatusOK, nil
+}
+
+func (as *AlarmScheduler) Delete(userID, id string) (bool, error) {
+	as.mutx.Lock()
+	defer as.mutx.Unlock()
+
+	alarm, ok := as.alarms[id]
+	if !ok || alarm.UserID != userID {
+		return false, nil
+	}
+	as.unschedule(id)
+	delete(as.alarms, id)
+	if err := as.save(); err != nil {
+		as.alarms[id] = alarm
+		as.schedule(alarm)
+		return false, err
+	}
+	return true, nil
+}
+
+// Get returns a copy of the user's alarm with its next run time filled in.
+func (as *AlarmScheduler) Get(userID, id string) (*Alarm, bool) {
+	as.mutx.Lock()
+	defer as.mutx.Unlock()
+
+	alarm, ok := as.alarms[id]
+	if !ok || alarm.UserID != userID {
+		return nil, false
+	}
+	return as.withNextRun(alarm), true
+}
+
+// List returns copies of the user's alarms with their next run times filled in.
+func (as *AlarmScheduler) List(userID string) []*Alarm {
+	as.mutx.Lock()
+	defer as.mutx.Unlock()
+
+	alarms := []*Alarm{}
+	for _, alarm := range as.alarms {
+		if alarm.UserID == userID {
+			alarms = append(alarms, as.withNextRun(alarm))
+		}
+	}
+	slices.SortFunc(alarms, func(a, b *Alarm) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return alarms
+}
+
+func (as *AlarmScheduler) withNextRun(alarm *Alarm) *Alarm {
+	listed := *alarm
+	if entry, ok := as.entries[alarm.ID]; ok {
+		if next := as.cron.Entry(entry).Next; !next.IsZero() {
+			listed.NextRunAt = &next
+		}
+	}
+	return &listed
+}
+
+// Run fires an alarm immediately, outside its schedule, and returns the step results.
+func (as *AlarmScheduler) Run(userID, id string) ([]StepResult, bool) {
+	as.mutx.Lock()
+	alarm, ok := as.alarms[id]
+	as.mutx.Unlock()
+	if !ok || alarm.UserID != userID {
+		return nil, false
+	}
+	return as.fire(id), true
+}
+
+func (as *AlarmScheduler) save() error {
+	alarms := make([]*Alarm, 0, len(as.alarms))
+	for _, alarm := range as.alarms {
+		saved := *alarm
+		saved.NextRunAt = nil
+		alarms = append(alarms, &saved)
+	}
+	slices.SortFunc(alarms, func(a, b *Alarm) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return saveJSON(as.path, alarms)
+}
+
+// fire runs an alarm for the logged-in user and records the outcome.
+func (as *AlarmScheduler) fire(id string) []StepResult {
+	as.mutx.Lock()
+	stored, ok := as.alarms[id]
+	if !ok {
+		as.mutx.Unlock()
+		return nil
+	}
+	alarm := *stored
+	as.mutx.Unlock()
+
+	var results []StepResult
+	accessToken, valid := as.tokenMx.GetToken()
+	if !valid || as.tokenMx.GetUser() != alarm.UserID {
+		results = []StepResult{{Step: "token", Status: http.StatusUnauthorized, Error: "alarm owner is not logged in"}}
+	} else {
+		results = as.runAlarm(accessToken, &alarm)
+	}
+
+	as.mutx.Lock()
+	defer as.mutx.Unlock()
+
+	if stored, ok := as.alarms[id]; ok {
+		now := time.Now().UTC()
+		stored.LastRunAt = &now
+		stored.LastResult = results
+		if err := as.save(); err != nil {
+			log.Printf("alarm: %v", err)
+		}
+	}
+	return results
+}
+
+// runAlarm performs the alarm's steps, stopping at the first failure. The volume ramp runs in the
+// background through the fader, so any later volume command stops it.
+func (as *AlarmScheduler) runAlarm(accessToken string, alarm *Alarm) []StepResult {
+	var results []StepResult
+	step := func(name string, status int, err error) bool {
+		results = append(results, stepResult(name, status, err))
+		return err == nil
+	}
+
+	deviceID, status, err := as.devices.Resolve(accessToken, alarm.UserID, alarm.DeviceID, alarm.DeviceName)
+	if !step("device", status, err) {
+		return results
+	}
+	status, err = TransferPlayback(accessToken, deviceID, false)
+	if !step("transfer", status, err) {
+		return results
+	}
+	// Both ends of the ramp are kept within the device's volume limit.
+	volume := as.limits.Clamp(alarm.UserID, deviceID, alarm.Volume)
+	startVolume := volume
+	if alarm.RampSeconds > 0 {
+		startVolume = as.limits.Clamp(alarm.UserID, deviceID, alarm.RampFrom)
+	}
+	status, err = SetPlaybackVolume(accessToken, deviceID, startVolume)
+	if !step("volume", status, err) {
+		return results
+	}
+	status, err = ToggleShuffle(accessToken, deviceID, alarm.Shuffle)
+	if !step("shuffle", status, err) {
+		return results
+	}
+	status, err = StartPlayback(accessToken, deviceID, PlaybackOptions{ContextURI: alarm.ContextURI})
+	if !step("play", status, err) {
+		return results
+	}
+	if alarm.RampSeconds > 0 {
+		ctx, done := as.fader.Begin()
+		go func() {
+			defer done()
+			_, err := FadeVolume(ctx, accessToken, deviceID, startVolume, volume, time.Duration(alarm.RampSeconds)*time.Second, CurveLinear)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("alarm %s ramp: %v", alarm.ID, err)
+			}
+		}()
+		step("ramp started", http.StatusAccepted, nil)
+	}
+	return results
+}
@@ -96,6 +96,7 @@ func GetProfile(accessToken, baseUrl string) (*UserProfile, error) {
 type TokenManager struct {
 	AccessToken string
 	ExpiresAt time.Time
+	UserID string
 	mutx	sync.RWMutex
 }
 
@@ -121,6 +122,22 @@ func (tm *TokenManager) GetToken() (string, bool) {
 	return "", false
 }
 
+// SetUser records the Spotify user ID the current token belongs to.
+func (tm *TokenManager) SetUser(userID string) {
+	tm.mutx.Lock()
+	defer tm.mutx.Unlock()
+
+	tm.UserID = userID
+}
+
+// GetUser returns the Spotify user ID of the logged-in user, or "" before the first login.
+func (tm *TokenManager) GetUser() string {
+	tm.mutx.RLock()
+	defer tm.mutx.RUnlock()
+
+	return tm.UserID
+}
+
 func (tm *TokenManager) RefreshToken(refreshFunc func() (string, int, error)) (string, error) {
 	tm.mutx.Lock()
 	defer tm.mutx.Unlock()
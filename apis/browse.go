@@ -0,0 +1,209 @@
+package api
+
+import (
+	"iter"
+	"net/url"
+	"strconv"
+)
+
+type Image struct {
+	URL    string `json:"url"`
+	Height int    `json:"height"`
+	Width  int    `json:"width"`
+}
+
+type Category struct {
+	Href  string  `json:"href"`
+	Icons []Image `json:"icons"`
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+}
+
+type CategoriesResponse struct {
+	Categories Paging[Category] `json:"categories"`
+}
+
+type SimplifiedPlaylist struct {
+	Collaborative bool              `json:"collaborative"`
+	Description   string            `json:"description"`
+	ExternalUrls  map[string]string `json:"external_urls"`
+	Href          string            `json:"href"`
+	ID            string            `json:"id"`
+	Images        []Image           `json:"images"`
+	Name          string            `json:"name"`
+	Public        bool              `json:"public"`
+	SnapshotID    string            `json:"snapshot_id"`
+	Type          string            `json:"type"`
+	URI           string            `json:"uri"`
+}
+
+type PlaylistsResponse struct {
+	Message   string                     `json:"message"`
+	Playlists Paging[SimplifiedPlaylist] `json:"playlists"`
+}
+
+type SimplifiedAlbum struct {
+	AlbumType    string            `json:"album_type"`
+	TotalTracks  int               `json:"total_tracks"`
+	ExternalUrls map[string]string `json:"external_urls"`
+	Href         string            `json:"href"`
+	ID           string            `json:"id"`
+	Images       []Image           `json:"images"`
+	Name         string            `json:"name"`
+	ReleaseDate  string            `json:"release_date"`
+	Type         string            `json:"type"`
+	URI          string            `json:"uri"`
+	Artists      []struct {
+		Name string `json:"name"`
+		ID   string `json:"id"`
+	} `json:"artists"`
+}
+
+type NewReleasesResponse struct {
+	Albums Paging[SimplifiedAlbum] `json:"albums"`
+}
+
+type MarketsResponse struct {
+	Markets []string `json:"markets"`
+}
+
+// BrowseOptions holds the optional query parameters shared by the browse endpoints.
+// Zero values are left out of the request so Spotify applies its own defaults.
+type BrowseOptions struct {
+	Locale  string
+	Country string
+	Limit   int
+	Offset  int
+}
+
+func (o BrowseOptions) values() url.Values {
+	params := url.Values{}
+	if o.Locale != "" {
+		params.Set("locale", o.Locale)
+	}
+	if o.Country != "" {
+		params.Set("country", o.Country)
+	}
+	if o.Limit > 0 {
+		params.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		params.Set("offset", strconv.Itoa(o.Offset))
+	}
+	return params
+}
+
+// browseURL builds the full request URL for a browse endpoint.
+func browseURL(endpoint string, params url.Values) string {
+	requestURL := BaseAPIURL + endpoint
+	if len(params) > 0 {
+		requestURL += "?" + params.Encode()
+	}
+	return requestURL
+}
+
+// browseGet performs an authenticated GET against the browse endpoints and decodes the response into out.
+func browseGet(accessToken, endpoint string, params url.Values, action string, out interface{}) (int, error) {
+	return getJSON(accessToken, browseURL(endpoint, params), action, out)
+}
+
+func GetCategories(accessToken string, opts BrowseOptions) (*CategoriesResponse, int, error) {
+	var results CategoriesResponse
+	status, err := browseGet(accessToken, "/browse/categories", opts.values(), "categories", &results)
+	if err != nil {
+		return nil, status, err
+	}
+	return &results, status, nil
+}
+
+func GetCategory(accessToken, categoryID string, opts BrowseOptions) (*Category, int, error) {
+	params := url.Values{}
+	if opts.Locale != "" {
+		params.Set("locale", opts.Locale)
+	}
+	if opts.Country != "" {
+		params.Set("country", opts.Country)
+	}
+	var results Category
+	status, err := browseGet(accessToken, "/browse/categories/"+url.PathEscape(categoryID), params, "category", &results)
+	if err != nil {
+		return nil, status, err
+	}
+	return &results, status, nil
+}
+
+func GetCategoryPlaylists(accessToken, categoryID string, opts BrowseOptions) (*PlaylistsResponse, int, error) {
+	var results PlaylistsResponse
+	endpoint := "/browse/categories/" + url.PathEscape(categoryID) + "/playlists"
+	status, err := browseGet(accessToken, endpoint, opts.values(), "category playlists", &results)
+	if err != nil {
+		return nil, status, err
+	}
+	return &results, status, nil
+}
+
+func GetFeaturedPlaylists(accessToken string, opts BrowseOptions) (*PlaylistsResponse, int, error) {
+	var results PlaylistsResponse
+	status, err := browseGet(accessToken, "/browse/featured-playlists", opts.values(), "featured playlists", &results)
+	if err != nil {
+		return nil, status, err
+	}
+	return &results, status, nil
+}
+
+func GetNewReleases(accessToken string, opts BrowseOptions) (*NewReleasesResponse, int, error) {
+	var results NewReleasesResponse
+	status, err := browseGet(accessToken, "/browse/new-releases", opts.values(), "new releases", &results)
+	if err != nil {
+		return nil, status, err
+	}
+	return &results, status, nil
+}
+
+func GetAvailableMarkets(accessToken string) (*MarketsResponse, int, error) {
+	var results MarketsResponse
+	status, err := browseGet(accessToken, "/markets", nil, "available markets", &results)
+	if err != nil {
+		return nil, status, err
+	}
+	return &results, status, nil
+}
+
+// AllCategories lazily walks every page of browse categories, starting at the page described by opts.
+func AllCategories(accessToken string, opts BrowseOptions) iter.Seq2[Category, error] {
+	return walkItems(browseURL("/browse/categories", opts.values()),
+		func(pageURL string) (*Paging[Category], int, error) {
+			var results CategoriesResponse
+			status, err := getJSON(accessToken, pageURL, "categories", &results)
+			return &results.Categories, status, err
+		})
+}
+
+// AllCategoryPlaylists lazily walks every page of playlists tagged with a category.
+func AllCategoryPlaylists(accessToken, categoryID string, opts BrowseOptions) iter.Seq2[SimplifiedPlaylist, error] {
+	endpoint := "/browse/categories/" + url.PathEscape(categoryID) + "/playlists"
+	return walkItems(browseURL(endpoint, opts.values()), playlistPageFetcher(accessToken, "category playlists"))
+}
+
+// AllFeaturedPlaylists lazily walks every page of featured playlists.
+func AllFeaturedPlaylists(accessToken string, opts BrowseOptions) iter.Seq2[SimplifiedPlaylist, error] {
+	return walkItems(browseURL("/browse/featured-playlists", opts.values()), playlistPageFetcher(accessToken, "featured playlists"))
+}
+
+// AllNewReleases lazily walks every page of new album releases.
+func AllNewReleases(accessToken string, opts BrowseOptions) iter.Seq2[SimplifiedAlbum, error] {
+	return walkItems(browseURL("/browse/new-releases", opts.values()),
+		func(pageURL string) (*Paging[SimplifiedAlbum], int, error) {
+			var results NewReleasesResponse
+			status, err := getJSON(accessToken, pageURL, "new releases", &results)
+			return &results.Albums, status, err
+		})
+}
+
+func playlistPageFetcher(accessToken, action string) func(string) (*Paging[SimplifiedPlaylist], int, error) {
+	return func(pageURL string) (*Paging[SimplifiedPlaylist], int, error) {
+		var results PlaylistsResponse
+		status, err := getJSON(accessToken, pageURL, action, &results)
+		return &results.Playlists, status, err
+	}
+}
@@ -2,12 +2,16 @@ package api
 
 import (
 	"os"
+	"path/filepath"
 )
 
 var (
 	ClientID     = os.Getenv("SPOTIFY_CLIENT_ID")
 	ClientSecret = os.Getenv("SPOTIFY_CLIENT_SECRET")
 	RedirectURI  = os.Getenv("SPOTIFY_REDIRECT_URI")
+	DataDir      = os.Getenv("BLASTBOOM_DATA_DIR")
+	// WSAllowedOrigins is a comma separated list of extra origins allowed to open the /ws socket.
+	WSAllowedOrigins = os.Getenv("BLASTBOOM_WS_ORIGINS")
 )
 
 const (
@@ -15,3 +19,12 @@ const (
 	BaseTokenURL = "https://accounts.spotify.com/api/token"
 	BaseAPIURL   = "https://api.spotify.com/v1"
 )
+
+// DataPath returns the path of a file in the service's data directory, which defaults to ./data.
+func DataPath(name string) string {
+	dir := DataDir
+	if dir == "" {
+		dir = "data"
+	}
+	return filepath.Join(dir, name)
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// DeviceResolver turns human friendly device names and per-user aliases into Spotify device IDs.
+// Aliases map a short name such as "kitchen" onto a device name and are kept in a JSON file.
+type DeviceResolver struct {
+	path    string
+	aliases map[string]map[string]string
+	mutx    sync.RWMutex
+}
+
+func NewDeviceResolver(path string) (*DeviceResolver, error) {
+	dr := &DeviceResolver{path: path, aliases: map[string]map[string]string{}}
+	if err := loadJSON(path, &dr.aliases); err != nil {
+		return nil, err
+	}
+	return dr, nil
+}
+
+// Aliases returns the user's aliases keyed by alias.
+func (dr *DeviceResolver) Aliases(userID string) map[string]string {
+	dr.mutx.RLock()
+	defer dr.mutx.RUnlock()
+
+	aliases := map[string]string{}
+	for alias, target := range dr.aliases[userID] {
+		aliases[alias] = target
+	}
+	return aliases
+}
+
+// SetAlias points alias at a device name, replacing any previous target.
+func (dr *DeviceResolver) SetAlias(userID, alias, deviceName string) (int, error) {
+	alias = strings.ToLower(strings.TrimSpace(alias))
+	deviceName = strings.TrimSpace(deviceName)
+	if alias == "" || deviceName == "" {
+		return http.StatusBadRequest, fmt.Errorf("alias and device_name are required")
+	}
+
+	dr.mutx.Lock()
+	defer dr.mutx.Unlock()
+
+	if dr.aliases[userID] == nil {
+		dr.aliases[userID] = map[string]string{}
+	}
+	previous, existed := dr.aliases[userID][alias]
+	dr.aliases[userID][alias] = deviceName
+	if err := saveJSON(dr.path, dr.aliases); err != nil {
+		if existed {
+			dr.aliases[userID][alias] = previous
+		} else {
+			delete(dr.aliases[userID], alias)
+		}
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// DeleteAlias removes an alias and reports whether it existed.
+func (dr *DeviceResolver) DeleteAlias(userID, alias string) (bool, error) {
+	alias = strings.ToLower(strings.TrimSpace(alias))
+
+	dr.mutx.Lock()
+	defer dr.mutx.Unlock()
+
+	target, ok := dr.aliases[userID][alias]
+	if !ok {
+		return false, nil
+	}
+	delete(dr.aliases[userID], alias)
+	if err := saveJSON(dr.path, dr.aliases); err != nil {
+		dr.aliases[userID][alias] = target
+		return false, err
+	}
+	return true, nil
+}
+
+// Resolve returns the device ID to send to Spotify. A non-empty deviceID is used as is, an empty
+// deviceName means the active device, and anything else is looked up among the available devices.
+func (dr *DeviceResolver) Resolve(accessToken, userID, deviceID, deviceName string) (string, int, error) {
+	if deviceID != "" || deviceName == "" {
+		return deviceID, http.StatusOK, nil
+	}
+	device, status, err := dr.Find(accessToken, userID, deviceName)
+	if err != nil {
+		return "", status, err
+	}
+	return device.ID, status, nil
+}
+
+// Find looks a device up by alias, ID or name. Names match case-insensitively, ignoring punctuation,
+// then by unique substring, then by the closest spelling within a small edit distance.
+func (dr *DeviceResolver) Find(accessToken, userID, query string) (*DeviceData, int, error) {
+	devices, status, err := GetDevices(accessToken)
+	if err != nil {
+		return nil, status, err
+	}
+	return dr.findIn(devices.Devices, userID, query)
+}
+
+// findIn is Find over a device list the caller already has.
+func (dr *DeviceResolver) findIn(devices []DeviceData, userID, query string) (*DeviceData, int, error) {
+	query = dr.expandAlias(userID, query)
+	matches := matchDevices(devices, query)
+	switch len(matches) {
+	case 1:
+		return &matches[0], http.StatusOK, nil
+	case 0:
+		return nil, http.StatusNotFound, fmt.Errorf("no device matches %q; available devices: %s", query, describeDevices(devices))
+	}
+	return nil, http.StatusConflict, fmt.Errorf("%q matches several devices: %s", query, describeDevices(matches))
+}
+
+// expandAlias returns the device name an alias points at, or query itself when it is not an alias.
+func (dr *DeviceResolver) expandAlias(userID, query string) string {
+	dr.mutx.RLock()
+	defer dr.mutx.RUnlock()
+
+	if target, ok := dr.aliases[userID][strings.ToLower(strings.TrimSpace(query))]; ok {
+		return target
+	}
+	return query
+}
+
+// matchDevices returns the devices matching query at the strictest level that has any match.
+func matchDevices(devices []DeviceData, query string) []DeviceData {
+	for _, device := range devices {
+		if device.ID == query {
+			return []DeviceData{device}
+		}
+	}
+	levels := []func(name, query string) bool{
+		strings.EqualFold,
+		func(name, query string) bool { return normalizeName(name) == normalizeName(query) },
+		func(name, query string) bool {
+			return normalizeName(query) != "" && strings.Contains(normalizeName(name), normalizeName(query))
+		},
+	}
+	for _, matches := range levels {
+		var found []DeviceData
+		for _, device := range devices {
+			if matches(device.Name, query) {
+				found = append(found, device)
+			}
+		}
+		if len(found) > 0 {
+			return found
+		}
+	}
+
+	normalized := normalizeName(query)
+	best, bestDistance := []DeviceData(nil), len(normalized)/3+1
+	for _, device := range devices {
+		distance := levenshtein(normalizeName(device.Name), normalized)
+		if distance < bestDistance {
+			best, bestDistance = []DeviceData{device}, distance
+		} else if distance == bestDistance && best != nil {
+			best = append(best, device)
+		}
+	}
+	return best
+}
+
+func describeDevices(devices []DeviceData) string {
+	if len(devices) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(devices))
+	for _, device := range devices {
+		names = append(names, fmt.Sprintf("%s (%s)", device.Name, device.Type))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// normalizeName lowercases name and drops everything but letters and digits.
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
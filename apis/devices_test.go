@@ -0,0 +1,62 @@
+package api
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"kitchen", "", 7},
+		{"", "tv", 2},
+		{"kitchen", "kitchen", 0},
+		{"kitchen", "kitchn", 1},
+		{"kitchen", "kitten", 2},
+		{"livingroom", "livngrom", 2},
+		{"bäd", "bad", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMatchDevices(t *testing.T) {
+	devices := []DeviceData{
+		{ID: "1", Name: "Kitchen Speaker"},
+		{ID: "2", Name: "Kitchen Display"},
+		{ID: "3", Name: "Living Room TV"},
+		{ID: "4", Name: "Alex's MacBook Pro"},
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"3", []string{"3"}},
+		{"kitchen speaker", []string{"1"}},
+		{"living-room tv", []string{"3"}},
+		{"alexs macbook pro", []string{"4"}},
+		{"macbook", []string{"4"}},
+		{"kitchen", []string{"1", "2"}},
+		{"livng room tv", []string{"3"}},
+		{"kitchn speakr", []string{"1"}},
+		{"bathroom", nil},
+		{"!!", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var got []string
+			for _, device := range matchDevices(devices, tt.query) {
+				got = append(got, device.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("matchDevices(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	EventTrackChanged   = "track_changed"
+	EventPaused         = "paused"
+	EventResumed        = "resumed"
+	EventSeeked         = "seeked"
+	EventDeviceChanged  = "device_changed"
+	EventVolumeChanged  = "volume_changed"
+	EventShuffleChanged = "shuffle_changed"
+	EventRepeatChanged  = "repeat_changed"
+	EventQueueChanged   = "queue_changed"
+)
+
+// seekTolerance is how far the reported progress may drift from the expected progress before it counts as a seek.
+const seekTolerance = 3 * time.Second
+
+// PlaybackEvent describes one change between two successive playback states.
+// State is the playback state after the change and is nil when nothing is playing.
+type PlaybackEvent struct {
+	Type  string            `json:"type"`
+	Time  time.Time         `json:"time"`
+	State *PlayBackResponse `json:"state"`
+	Queue []*Track          `json:"queue,omitempty"`
+}
+
+// PlaybackWatcher runs a single poller on the logged-in user's playback state and fans out
+// the changes it detects to every subscriber. The poller only runs while someone is subscribed.
+type PlaybackWatcher struct {
+	tokenMx     *TokenManager
+	interval    time.Duration
+	subscribers map[chan PlaybackEvent]struct{}
+	cancel      context.CancelFunc
+	current     *PlayBackResponse
+	queue       []*Track
+	mutx        sync.Mutex
+}
+
+func NewPlaybackWatcher(tokenMx *TokenManager, interval time.Duration) *PlaybackWatcher {
+	return &PlaybackWatcher{
+		tokenMx:     tokenMx,
+		interval:    interval,
+		subscribers: map[chan PlaybackEvent]struct{}{},
+	}
+}
+
+// Subscribe registers a new listener and returns its event channel together with a function that removes it.
+// Slow listeners miss events rather than blocking the poller.
+func (pw *PlaybackWatcher) Subscribe() (<-chan PlaybackEvent, func()) {
+	pw.mutx.Lock()
+	defer pw.mutx.Unlock()
+
+	events := make(chan PlaybackEvent, 16)
+	pw.subscribers[events] = struct{}{}
+	if pw.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		pw.cancel = cancel
+		go pw.run(ctx)
+	}
+
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			pw.mutx.Lock()
+			defer pw.mutx.Unlock()
+
+			delete(pw.subscribers, events)
+			close(events)
+			if len(pw.subscribers) == 0 && pw.cancel != nil {
+				pw.cancel()
+				pw.cancel = nil
+				pw.current, pw.queue = nil, nil
+			}
+		})
+	}
+}
+
+// Current returns the most recently polled playback state, or nil if none is known.
+func (pw *PlaybackWatcher) Current() *PlayBackResponse {
+	pw.mutx.Lock()
+	defer pw.mutx.Unlock()
+
+	return pw.current
+}
+
+func (pw *PlaybackWatcher) run(ctx context.Context) {
+	ticker := time.NewTicker(pw.interval)
+	defer ticker.Stop()
+
+	first := true
+	lastPoll := time.Now()
+	for {
+		if err := pw.poll(ctx, first, time.Since(lastPoll)); err != nil {
+			log.Printf("playback watcher: %v", err)
+		} else {
+			first = false
+		}
+		lastPoll = time.Now()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll fetches the playback state and queue and publishes the differences from the previous poll.
+// The first successful poll only records the baseline.
+func (pw *PlaybackWatcher) poll(ctx context.Context, first bool, elapsed time.Duration) error {
+	accessToken, valid := pw.tokenMx.GetToken()
+	if !valid {
+		return nil
+	}
+
+	state, status, err := GetPlayBack(accessToken)
+	if err != nil && status != http.StatusNoContent {
+		return err
+	}
+	var queue []*Track
+	if state != nil {
+		queueResp, _, err := GetUsersQueue(accessToken)
+		if err != nil {
+			return err
+		}
+		queue = queueResp.Queue
+	}
+
+	pw.mutx.Lock()
+	defer pw.mutx.Unlock()
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	previous, previousQueue := pw.current, pw.queue
+	pw.current, pw.queue = state, queue
+	if first {
+		return nil
+	}
+
+	now := time.Now()
+	for _, eventType := range DiffPlayback(previous, state, elapsed) {
+		pw.publish(PlaybackEvent{Type: eventType, Time: now, State: state})
+	}
+	if !sameTracks(previousQueue, queue) {
+		pw.publish(PlaybackEvent{Type: EventQueueChanged, Time: now, State: state, Queue: queue})
+	}
+	return nil
+}
+
+func (pw *PlaybackWatcher) publish(event PlaybackEvent) {
+	for subscriber := range pw.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+// DiffPlayback lists the event types that explain the change from previous to next,
+// given the time elapsed between the two polls. Either state may be nil when nothing is playing.
+func DiffPlayback(previous, next *PlayBackResponse, elapsed time.Duration) []string {
+	var events []string
+	if previous == nil && next == nil {
+		return events
+	}
+	if previous == nil || next == nil {
+		events = append(events, EventDeviceChanged)
+		playing := next != nil && next.IsPlaying
+		wasPlaying := previous != nil && previous.IsPlaying
+		if playing && !wasPlaying {
+			events = append(events, EventResumed)
+		} else if wasPlaying && !playing {
+			events = append(events, EventPaused)
+		}
+		if trackID(previous) != trackID(next) {
+			events = append(events, EventTrackChanged)
+		}
+		return events
+	}
+
+	if trackID(previous) != trackID(next) {
+		events = append(events, EventTrackChanged)
+	} else if isSeek(previous, next, elapsed) {
+		events = append(events, EventSeeked)
+	}
+	if previous.IsPlaying && !next.IsPlaying {
+		events = append(events, EventPaused)
+	} else if !previous.IsPlaying && next.IsPlaying {
+		events = append(events, EventResumed)
+	}
+	if deviceID(previous) != deviceID(next) {
+		events = append(events, EventDeviceChanged)
+	} else if previous.Device != nil && next.Device != nil && previous.Device.VolumePercent != next.Device.VolumePercent {
+		events = append(events, EventVolumeChanged)
+	}
+	if previous.ShuffleState != next.ShuffleState {
+		events = append(events, EventShuffleChanged)
+	}
+	if previous.RepeatState != next.RepeatState {
+		events = append(events, EventRepeatChanged)
+	}
+	return events
+}
+
+// isSeek reports whether the progress on the same track moved further than playback alone explains.
+func isSeek(previous, next *PlayBackResponse, elapsed time.Duration) bool {
+	expected := time.Duration(previous.ProgressMS) * time.Millisecond
+	if previous.IsPlaying {
+		expected += elapsed
+	}
+	actual := time.Duration(next.ProgressMS) * time.Millisecond
+	drift := actual - expected
+	if drift < 0 {
+		drift = -drift
+	}
+	return drift > seekTolerance
+}
+
+func trackID(state *PlayBackResponse) string {
+	if state == nil || state.Item == nil {
+		return ""
+	}
+	return state.Item.ID
+}
+
+func deviceID(state *PlayBackResponse) string {
+	if state == nil || state.Device == nil {
+		return ""
+	}
+	return state.Device.ID
+}
+
+func sameTracks(a, b []*Track) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if (a[i] == nil) != (b[i] == nil) || (a[i] != nil && a[i].ID != b[i].ID) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func playback(trackID, deviceID string, progressMS uint64, playing bool) *PlayBackResponse {
+	return &PlayBackResponse{
+		Device:      &DeviceData{ID: deviceID, VolumePercent: 50},
+		RepeatState: "off",
+		ProgressMS:  progressMS,
+		IsPlaying:   playing,
+		Item:        &Track{ID: trackID},
+	}
+}
+
+func TestDiffPlayback(t *testing.T) {
+	louder := playback("a", "d1", 10000, true)
+	louder.Device.VolumePercent = 80
+	shuffled := playback("a", "d1", 10000, true)
+	shuffled.ShuffleState = true
+	repeating := playback("a", "d1", 10000, true)
+	repeating.RepeatState = "track"
+
+	tests := []struct {
+		name     string
+		previous *PlayBackResponse
+		next     *PlayBackResponse
+		elapsed  time.Duration
+		want     []string
+	}{
+		{"nothing playing", nil, nil, time.Second, nil},
+		{"steady playback", playback("a", "d1", 10000, true), playback("a", "d1", 12000, true), 2 * time.Second, nil},
+		{"small drift", playback("a", "d1", 10000, true), playback("a", "d1", 14000, true), 2 * time.Second, nil},
+		{"seek forward", playback("a", "d1", 10000, true), playback("a", "d1", 60000, true), 2 * time.Second, []string{EventSeeked}},
+		{"seek while paused", playback("a", "d1", 10000, false), playback("a", "d1", 5000, false), 2 * time.Second, []string{EventSeeked}},
+		{"track changed", playback("a", "d1", 200000, true), playback("b", "d1", 1000, true), 2 * time.Second, []string{EventTrackChanged}},
+		{"paused", playback("a", "d1", 10000, true), playback("a", "d1", 10000, false), 2 * time.Second, []string{EventPaused}},
+		{"resumed", playback("a", "d1", 10000, false), playback("a", "d1", 10000, true), 2 * time.Second, []string{EventResumed}},
+		{"device changed", playback("a", "d1", 10000, true), playback("a", "d2", 12000, true), 2 * time.Second, []string{EventDeviceChanged}},
+		{"volume changed", playback("a", "d1", 10000, true), louder, 0, []string{EventVolumeChanged}},
+		{"shuffle changed", playback("a", "d1", 10000, true), shuffled, 0, []string{EventShuffleChanged}},
+		{"repeat changed", playback("a", "d1", 10000, true), repeating, 0, []string{EventRepeatChanged}},
+		{"playback started", nil, playback("a", "d1", 0, true), time.Second, []string{EventDeviceChanged, EventResumed, EventTrackChanged}},
+		{"playback stopped", playback("a", "d1", 10000, true), nil, time.Second, []string{EventDeviceChanged, EventPaused, EventTrackChanged}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffPlayback(tt.previous, tt.next, tt.elapsed); !slices.Equal(got, tt.want) {
+				t.Errorf("DiffPlayback() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
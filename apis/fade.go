@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// fadeStep is the pause between two volume changes while fading, which keeps fades within Spotify's rate limits.
+const fadeStep = time.Second
+
+// FadeCurve shapes how the volume moves between the start and target of a fade.
+type FadeCurve string
+
+const (
+	CurveLinear      FadeCurve = "linear"
+	CurveEaseIn      FadeCurve = "ease-in"
+	CurveEaseOut     FadeCurve = "ease-out"
+	CurveEaseInOut   FadeCurve = "ease-in-out"
+	CurveExponential FadeCurve = "exponential"
+)
+
+// ParseFadeCurve accepts the curve names above, with an empty name meaning linear.
+func ParseFadeCurve(name string) (FadeCurve, error) {
+	switch curve := FadeCurve(name); curve {
+	case "":
+		return CurveLinear, nil
+	case CurveLinear, CurveEaseIn, CurveEaseOut, CurveEaseInOut, CurveExponential:
+		return curve, nil
+	}
+	return "", fmt.Errorf("unknown curve %q, expected linear, ease-in, ease-out, ease-in-out or exponential", name)
+}
+
+// at maps the elapsed fraction t of a fade to the fraction of the volume change applied so far.
+func (c FadeCurve) at(t float64) float64 {
+	switch c {
+	case CurveEaseIn:
+		return t * t
+	case CurveEaseOut:
+		return 1 - (1-t)*(1-t)
+	case CurveEaseInOut:
+		return (1 - math.Cos(math.Pi*t)) / 2
+	case CurveExponential:
+		return (math.Pow(2, 10*t) - 1) / 1023
+	}
+	return t
+}
+
+// FadeVolume moves the volume of deviceID from one level to another over duration, one step at a time.
+// It stops early with ctx's error when ctx is cancelled, leaving the volume where it got to.
+func FadeVolume(ctx context.Context, accessToken, deviceID string, from, to int, duration time.Duration, curve FadeCurve) (int, error) {
+	steps := int(duration / fadeStep)
+	if steps < 1 {
+		steps = 1
+	}
+	last := from
+	for i := 1; i <= steps; i++ {
+		volume := from + int(math.Round(float64(to-from)*curve.at(float64(i)/float64(steps))))
+		if volume != last || i == steps {
+			if status, err := SetPlaybackVolume(accessToken, deviceID, volume); err != nil {
+				return status, err
+			}
+			last = volume
+		}
+		if i == steps {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return http.StatusConflict, ctx.Err()
+		case <-time.After(duration / time.Duration(steps)):
+		}
+	}
+	return http.StatusNoContent, nil
+}
+
+// FadeSkip fades the volume out, skips to the next track and fades back in to the starting volume,
+// each fade taking duration. Both ends are kept within the device's volume limit, so a device with a
+// minimum only fades down to it. A cancelled fade-out stops before skipping.
+func FadeSkip(ctx context.Context, accessToken, userID, deviceID string, limits *VolumeLimits, duration time.Duration, curve FadeCurve) ([]StepResult, bool) {
+	var results []StepResult
+	step := func(name string, status int, err error) bool {
+		results = append(results, stepResult(name, status, err))
+		return err == nil
+	}
+
+	device, status, err := VolumeDevice(accessToken, deviceID)
+	if !step("volume", status, err) {
+		return results, false
+	}
+	quiet := limits.Clamp(userID, device.ID, 0)
+	volume := limits.Clamp(userID, device.ID, int(device.VolumePercent))
+	status, err = FadeVolume(ctx, accessToken, device.ID, int(device.VolumePercent), quiet, duration, curve)
+	if !step("fade-out", status, err) {
+		return results, false
+	}
+	status, err = SkipNext(accessToken, device.ID)
+	if !step("next", status, err) {
+		SetPlaybackVolume(accessToken, device.ID, volume)
+		return results, false
+	}
+	status, err = FadeVolume(ctx, accessToken, device.ID, quiet, volume, duration, curve)
+	return results, step("fade-in", status, err)
+}
+
+// DeviceVolume returns the current volume of deviceID, or of the active device when deviceID is empty.
+// It fails without touching the volume when the device does not support volume control.
+func DeviceVolume(accessToken, deviceID string) (int, int, error) {
+	device, status, err := VolumeDevice(accessToken, deviceID)
+	if err != nil {
+		return 0, status, err
+	}
+	return int(device.VolumePercent), status, nil
+}
+
+// VolumeDevice looks up deviceID, or the active device when deviceID is empty, and checks that its volume can be changed.
+func VolumeDevice(accessToken, deviceID string) (*DeviceData, int, error) {
+	devices, status, err := GetDevices(accessToken)
+	if err != nil {
+		return nil, status, err
+	}
+	for _, device := range devices.Devices {
+		if (deviceID == "" && device.IsActive) || (deviceID != "" && device.ID == deviceID) {
+			if !device.SupportsVolume {
+				return nil, http.StatusConflict, fmt.Errorf("device %q does not support volume control", device.Name)
+			}
+			return &device, http.StatusOK, nil
+		}
+	}
+	if deviceID == "" {
+		return nil, http.StatusNotFound, fmt.Errorf("no active device")
+	}
+	return nil, http.StatusNotFound, fmt.Errorf("device %q is not available", deviceID)
+}
+
+// VolumeFader makes sure only one user-started fade runs at a time. Starting a fade, or any
+// other volume command calling Cancel, stops the fade that is currently running.
+type VolumeFader struct {
+	cancel     context.CancelFunc
+	generation uint64
+	mutx       sync.Mutex
+}
+
+func NewVolumeFader() *VolumeFader {
+	return &VolumeFader{}
+}
+
+// Begin cancels the running fade and returns the context for a new one.
+// The returned function must be called when the new fade ends.
+func (vf *VolumeFader) Begin() (context.Context, func()) {
+	vf.mutx.Lock()
+	defer vf.mutx.Unlock()
+
+	if vf.cancel != nil {
+		vf.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	vf.cancel = cancel
+	vf.generation++
+	generation := vf.generation
+	return ctx, func() {
+		vf.mutx.Lock()
+		defer vf.mutx.Unlock()
+
+		cancel()
+		if vf.generation == generation {
+			vf.cancel = nil
+		}
+	}
+}
+
+// Cancel stops the running fade and reports whether there was one.
+func (vf *VolumeFader) Cancel() bool {
+	vf.mutx.Lock()
+	defer vf.mutx.Unlock()
+
+	if vf.cancel == nil {
+		return false
+	}
+	vf.cancel()
+	vf.cancel = nil
+	return true
+}
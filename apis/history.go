@@ -0,0 +1,239 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PlayRecord is a single play kept in the local listening history.
+type PlayRecord struct {
+	UserID   string    `json:"user_id"`
+	PlayedAt time.Time `json:"played_at"`
+	Track    *Track    `json:"track"`
+	Context  *Context  `json:"context"`
+}
+
+func (r PlayRecord) key() string {
+	return r.UserID + "|" + r.PlayedAt.UTC().Format(time.RFC3339Nano)
+}
+
+// HistoryStore is an append-only listening history persisted as JSON lines.
+// Records are deduplicated per user by their played_at timestamp.
+type HistoryStore struct {
+	path    string
+	records []PlayRecord
+	seen    map[string]struct{}
+	mutx    sync.RWMutex
+}
+
+// NewHistoryStore opens the history file at path, loading any records already on disk.
+func NewHistoryStore(path string) (*HistoryStore, error) {
+	hs := &HistoryStore{path: path, seen: map[string]struct{}{}}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return hs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record PlayRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("failed to parse history: %w", err)
+		}
+		if _, ok := hs.seen[record.key()]; ok {
+			continue
+		}
+		hs.seen[record.key()] = struct{}{}
+		hs.records = append(hs.records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	sort.SliceStable(hs.records, func(i, j int) bool {
+		return hs.records[i].PlayedAt.Before(hs.records[j].PlayedAt)
+	})
+	return hs, nil
+}
+
+// Append writes the records that are not already stored and returns how many were added.
+func (hs *HistoryStore) Append(records []PlayRecord) (int, error) {
+	hs.mutx.Lock()
+	defer hs.mutx.Unlock()
+
+	// Keys are only marked as seen once the write succeeds, so a failed append is retried on the next poll.
+	var fresh []PlayRecord
+	batch := map[string]struct{}{}
+	for _, record := range records {
+		if _, ok := hs.seen[record.key()]; ok {
+			continue
+		}
+		if _, ok := batch[record.key()]; ok {
+			continue
+		}
+		batch[record.key()] = struct{}{}
+		fresh = append(fresh, record)
+	}
+	if len(fresh) == 0 {
+		return 0, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(hs.path), 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	file, err := os.OpenFile(hs.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, record := range fresh {
+		if err := encoder.Encode(record); err != nil {
+			return 0, fmt.Errorf("failed to write history: %w", err)
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to write history: %w", err)
+	}
+
+	for key := range batch {
+		hs.seen[key] = struct{}{}
+	}
+	hs.records = append(hs.records, fresh...)
+	sort.SliceStable(hs.records, func(i, j int) bool {
+		return hs.records[i].PlayedAt.Before(hs.records[j].PlayedAt)
+	})
+	return len(fresh), nil
+}
+
+// Range returns the user's plays between from and to, oldest first. Zero times leave that side unbounded.
+func (hs *HistoryStore) Range(userID string, from, to time.Time) []PlayRecord {
+	hs.mutx.RLock()
+	defer hs.mutx.RUnlock()
+
+	records := []PlayRecord{}
+	for _, record := range hs.records {
+		if record.UserID != userID {
+			continue
+		}
+		if !from.IsZero() && record.PlayedAt.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !record.PlayedAt.Before(to) {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// Latest returns the time of the user's most recent stored play, or the zero time if there is none.
+func (hs *HistoryStore) Latest(userID string) time.Time {
+	hs.mutx.RLock()
+	defer hs.mutx.RUnlock()
+
+	for i := len(hs.records) - 1; i >= 0; i-- {
+		if hs.records[i].UserID == userID {
+			return hs.records[i].PlayedAt
+		}
+	}
+	return time.Time{}
+}
+
+// HistoryRecorder periodically copies the logged-in user's recently played tracks into a HistoryStore,
+// so plays are kept beyond the 50 items Spotify remembers.
+type HistoryRecorder struct {
+	tokenMx  *TokenManager
+	store    *HistoryStore
+	interval time.Duration
+}
+
+func NewHistoryRecorder(tokenMx *TokenManager, store *HistoryStore, interval time.Duration) *HistoryRecorder {
+	return &HistoryRecorder{tokenMx: tokenMx, store: store, interval: interval}
+}
+
+// Start polls in the background until ctx is cancelled.
+func (hr *HistoryRecorder) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(hr.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := hr.Poll(); err != nil {
+				log.Printf("history: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Poll fetches every play since the latest recorded one and stores it, returning how many were added.
+// It does nothing while no user is logged in.
+func (hr *HistoryRecorder) Poll() (int, error) {
+	accessToken, valid := hr.tokenMx.GetToken()
+	userID := hr.tokenMx.GetUser()
+	if !valid || userID == "" {
+		return 0, nil
+	}
+
+	items, _, err := RecentlyPlayedBetween(accessToken, hr.store.Latest(userID), time.Time{})
+	if err != nil {
+		return 0, err
+	}
+	return hr.store.appendItems(userID, items)
+}
+
+// PollLatest is a cheap Poll for request paths: it stores only the one page of plays Spotify returns
+// after the latest recorded one, leaving anything older to the background poll.
+func (hr *HistoryRecorder) PollLatest() (int, error) {
+	accessToken, valid := hr.tokenMx.GetToken()
+	userID := hr.tokenMx.GetUser()
+	if !valid || userID == "" {
+		return 0, nil
+	}
+
+	opts := RecentlyPlayedOptions{Limit: 50}
+	if latest := hr.store.Latest(userID); !latest.IsZero() {
+		opts.After = latest.UnixMilli()
+	}
+	page, _, err := GetRecentlyPlayed(accessToken, opts)
+	if err != nil {
+		return 0, err
+	}
+	return hr.store.appendItems(userID, page.Items)
+}
+
+// appendItems stores recently played items as the user's plays.
+func (hs *HistoryStore) appendItems(userID string, items []RecentlyPlayedItem) (int, error) {
+	records := make([]PlayRecord, 0, len(items))
+	for _, item := range items {
+		playedAt, err := item.PlayedAtTime()
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse played_at: %w", err)
+		}
+		records = append(records, PlayRecord{
+			UserID:   userID,
+			PlayedAt: playedAt,
+			Track:    item.Track,
+			Context:  item.Context,
+		})
+	}
+	return hs.Append(records)
+}
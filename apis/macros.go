@@ -0,0 +1,307 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxMacroSteps = 25
+	// maxMacroWait caps the wait steps of a macro together so a run cannot hold its request open for long.
+	maxMacroWait = 10 * time.Second
+)
+
+var macroNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// MacroActions are the player steps a macro can run.
+var MacroActions = []string{"transfer", "volume", "shuffle", "repeat", "play", "pause", "next", "previous", "seek", "queue", "wait"}
+
+// MacroStep is one player call in a macro. Which fields are used depends on Action:
+// transfer takes a device and Play, volume takes Volume, shuffle takes State "on" or "off" and repeat
+// "track", "context" or "off" (both toggle when State is empty), play takes ContextURI or URIs and resumes
+// without them, seek takes PositionMS, queue takes URI and wait takes WaitMS.
+// Every step except wait can target a device; without one it goes to the active device.
+type MacroStep struct {
+	Action     string   `json:"action"`
+	DeviceID   string   `json:"device_id,omitempty"`
+	DeviceName string   `json:"device_name,omitempty"`
+	Play       bool     `json:"play,omitempty"`
+	Volume     *int     `json:"volume,omitempty"`
+	State      string   `json:"state,omitempty"`
+	ContextURI string   `json:"context_uri,omitempty"`
+	URIs       []string `json:"uris,omitempty"`
+	PositionMS *int     `json:"position_ms,omitempty"`
+	URI        string   `json:"uri,omitempty"`
+	WaitMS     int      `json:"wait_ms,omitempty"`
+	// ContinueOnError lets the macro go on when this step fails. By default a failed step stops the run.
+	ContinueOnError bool `json:"continue_on_error,omitempty"`
+}
+
+func (s *MacroStep) Validate() error {
+	switch s.Action {
+	case "transfer":
+		if s.DeviceID == "" && s.DeviceName == "" {
+			return fmt.Errorf("transfer needs device_id or device_name")
+		}
+	case "volume":
+		if s.Volume == nil || *s.Volume < 0 || *s.Volume > 100 {
+			return fmt.Errorf("volume must be between 0 and 100")
+		}
+	case "shuffle":
+		if s.State != "" && s.State != "on" && s.State != "off" {
+			return fmt.Errorf("shuffle state must be on or off")
+		}
+	case "repeat":
+		if s.State != "" && s.State != "track" && s.State != "context" && s.State != "off" {
+			return fmt.Errorf("repeat state must be track, context or off")
+		}
+	case "play":
+		if err := s.playbackOptions().Validate(); err != nil {
+			return err
+		}
+	case "pause", "next", "previous":
+	case "seek":
+		if s.PositionMS == nil || *s.PositionMS < 0 {
+			return fmt.Errorf("seek needs a position_ms of at least 0")
+		}
+	case "queue":
+		if err := ValidateSpotifyURI(s.URI, PlayableURITypes...); err != nil {
+			return fmt.Errorf("uri: %w", err)
+		}
+	case "wait":
+		if s.WaitMS <= 0 || time.Duration(s.WaitMS)*time.Millisecond > maxMacroWait {
+			return fmt.Errorf("wait_ms must be between 1 and %d", maxMacroWait.Milliseconds())
+		}
+		if s.DeviceID != "" || s.DeviceName != "" {
+			return fmt.Errorf("wait does not take a device")
+		}
+	default:
+		return fmt.Errorf("unknown action %q, expected one of %s", s.Action, strings.Join(MacroActions, ", "))
+	}
+	return nil
+}
+
+func (s *MacroStep) playbackOptions() PlaybackOptions {
+	opts := PlaybackOptions{ContextURI: s.ContextURI, URIs: s.URIs}
+	if s.PositionMS != nil {
+		opts.PositionMS = *s.PositionMS
+	}
+	return opts
+}
+
+// describe names the call a step makes, for its result.
+func (s *MacroStep) describe() string {
+	switch s.Action {
+	case "volume":
+		return fmt.Sprintf("volume %d", *s.Volume)
+	case "shuffle", "repeat":
+		if s.State == "" {
+			return s.Action + " toggle"
+		}
+		return s.Action + " " + s.State
+	case "play":
+		if s.ContextURI != "" {
+			return "play " + s.ContextURI
+		}
+		if len(s.URIs) > 0 {
+			return "play " + strings.Join(s.URIs, ", ")
+		}
+		return "resume"
+	case "seek":
+		return fmt.Sprintf("seek %d", *s.PositionMS)
+	case "queue":
+		return "queue " + s.URI
+	case "wait":
+		return fmt.Sprintf("wait %dms", s.WaitMS)
+	}
+	return s.Action
+}
+
+// Macro is a named, ordered list of player steps.
+type Macro struct {
+	Name      string      `json:"name"`
+	Steps     []MacroStep `json:"steps"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// MacroStepResult reports one step of a macro run. Skipped steps were not run because an earlier step failed.
+type MacroStepResult struct {
+	StepResult
+	Index    int    `json:"index"`
+	DeviceID string `json:"device_id,omitempty"`
+	Skipped  bool   `json:"skipped,omitempty"`
+}
+
+// Macros keeps each user's macros in a JSON file and runs them.
+type Macros struct {
+	devices *DeviceResolver
+	limits  *VolumeLimits
+	path    string
+	macros  map[string]map[string]*Macro
+	mutx    sync.RWMutex
+}
+
+func NewMacros(devices *DeviceResolver, limits *VolumeLimits, path string) (*Macros, error) {
+	m := &Macros{devices: devices, limits: limits, path: path, macros: map[string]map[string]*Macro{}}
+	if err := loadJSON(path, &m.macros); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// List returns the user's macros ordered by name.
+func (m *Macros) List(userID string) []*Macro {
+	m.mutx.RLock()
+	defer m.mutx.RUnlock()
+
+	macros := []*Macro{}
+	for _, macro := range m.macros[userID] {
+		macros = append(macros, macro)
+	}
+	slices.SortFunc(macros, func(a, b *Macro) int { return strings.Compare(a.Name, b.Name) })
+	return macros
+}
+
+// Get returns the user's macro with the given name.
+func (m *Macros) Get(userID, name string) (*Macro, bool) {
+	m.mutx.RLock()
+	defer m.mutx.RUnlock()
+
+	macro, ok := m.macros[userID][name]
+	return macro, ok
+}
+
+// Set stores a macro under name, replacing any previous one, and reports whether it replaced one.
+func (m *Macros) Set(userID, name string, steps []MacroStep) (*Macro, bool, int, error) {
+	if !macroNamePattern.MatchString(name) {
+		return nil, false, http.StatusBadRequest, fmt.Errorf("macro names are 1 to 64 letters, digits, dashes or underscores")
+	}
+	if len(steps) == 0 || len(steps) > maxMacroSteps {
+		return nil, false, http.StatusBadRequest, fmt.Errorf("a macro needs between 1 and %d steps", maxMacroSteps)
+	}
+	var wait time.Duration
+	for i := range steps {
+		if err := steps[i].Validate(); err != nil {
+			return nil, false, http.StatusBadRequest, fmt.Errorf("steps[%d]: %w", i, err)
+		}
+		wait += time.Duration(steps[i].WaitMS) * time.Millisecond
+	}
+	if wait > maxMacroWait {
+		return nil, false, http.StatusBadRequest, fmt.Errorf("the wait steps add up to more than %d ms", maxMacroWait.Milliseconds())
+	}
+	macro := &Macro{Name: name, Steps: steps, UpdatedAt: time.Now().UTC()}
+
+	m.mutx.Lock()
+	defer m.mutx.Unlock()
+
+	if m.macros[userID] == nil {
+		m.macros[userID] = map[string]*Macro{}
+	}
+	previous, existed := m.macros[userID][name]
+	m.macros[userID][name] = macro
+	if err := saveJSON(m.path, m.macros); err != nil {
+		if existed {
+			m.macros[userID][name] = previous
+		} else {
+			delete(m.macros[userID], name)
+		}
+		return nil, false, http.StatusInternalServerError, err
+	}
+	return macro, existed, http.StatusOK, nil
+}
+
+// Delete removes the user's macro and reports whether it existed.
+func (m *Macros) Delete(userID, name string) (bool, error) {
+	m.mutx.Lock()
+	defer m.mutx.Unlock()
+
+	macro, ok := m.macros[userID][name]
+	if !ok {
+		return false, nil
+	}
+	delete(m.macros[userID], name)
+	if err := saveJSON(m.path, m.macros); err != nil {
+		m.macros[userID][name] = macro
+		return false, err
+	}
+	return true, nil
+}
+
+// Run executes the macro's steps in order. A failed step stops the run unless it has ContinueOnError set,
+// and the steps after it are reported as skipped. With dryRun set only the devices are resolved and nothing
+// is sent to the player. Once ctx is cancelled a running wait ends and the remaining steps are skipped.
+// The returned bool is false if any step failed.
+func (m *Macros) Run(ctx context.Context, accessToken, userID string, macro *Macro, dryRun bool) ([]MacroStepResult, bool) {
+	results := make([]MacroStepResult, 0, len(macro.Steps))
+	ok, stopped := true, false
+	for i := range macro.Steps {
+		step := &macro.Steps[i]
+		result := MacroStepResult{StepResult: StepResult{Step: step.describe()}, Index: i}
+		if stopped || ctx.Err() != nil {
+			result.Skipped = true
+			results = append(results, result)
+			continue
+		}
+
+		deviceID, status, err := m.devices.Resolve(accessToken, userID, step.DeviceID, step.DeviceName)
+		result.DeviceID = deviceID
+		if err == nil && !dryRun {
+			status, err = m.runStep(ctx, accessToken, userID, deviceID, step)
+		}
+		result.StepResult = stepResult(result.Step, status, err)
+		results = append(results, result)
+		if err != nil {
+			ok = false
+			stopped = !step.ContinueOnError
+		}
+	}
+	return results, ok
+}
+
+func (m *Macros) runStep(ctx context.Context, accessToken, userID, deviceID string, step *MacroStep) (int, error) {
+	switch step.Action {
+	case "transfer":
+		return TransferPlayback(accessToken, deviceID, step.Play)
+	case "volume":
+		_, status, err := m.limits.SetVolume(accessToken, userID, deviceID, *step.Volume, false)
+		return status, err
+	case "shuffle":
+		if step.State == "" {
+			_, status, err := FlipShuffle(accessToken, deviceID)
+			return status, err
+		}
+		return ToggleShuffle(accessToken, deviceID, step.State == "on")
+	case "repeat":
+		if step.State == "" {
+			_, status, err := CycleRepeat(accessToken, deviceID)
+			return status, err
+		}
+		return ToggleRepeat(accessToken, deviceID, step.State)
+	case "play":
+		return StartPlayback(accessToken, deviceID, step.playbackOptions())
+	case "pause":
+		return PausePlayback(accessToken, deviceID)
+	case "next":
+		return SkipNext(accessToken, deviceID)
+	case "previous":
+		return SkipPrev(accessToken, deviceID)
+	case "seek":
+		return SeekPosition(accessToken, deviceID, *step.PositionMS)
+	case "queue":
+		return AddToQueue(accessToken, deviceID, step.URI)
+	case "wait":
+		select {
+		case <-ctx.Done():
+			return http.StatusConflict, ctx.Err()
+		case <-time.After(time.Duration(step.WaitMS) * time.Millisecond):
+			return http.StatusOK, nil
+		}
+	}
+	return http.StatusBadRequest, fmt.Errorf("unknown action %q", step.Action)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestMacroStepValidate(t *testing.T) {
+	zero, fifty, over, negative := 0, 50, 101, -1
+	tests := []struct {
+		name    string
+		step    MacroStep
+		wantErr bool
+	}{
+		{"transfer by name", MacroStep{Action: "transfer", DeviceName: "kitchen"}, false},
+		{"transfer without a device", MacroStep{Action: "transfer", Play: true}, true},
+		{"volume", MacroStep{Action: "volume", Volume: &fifty}, false},
+		{"volume zero", MacroStep{Action: "volume", Volume: &zero}, false},
+		{"volume missing", MacroStep{Action: "volume"}, true},
+		{"volume too loud", MacroStep{Action: "volume", Volume: &over}, true},
+		{"shuffle toggle", MacroStep{Action: "shuffle"}, false},
+		{"shuffle on", MacroStep{Action: "shuffle", State: "on"}, false},
+		{"shuffle bad state", MacroStep{Action: "shuffle", State: "track"}, true},
+		{"repeat context", MacroStep{Action: "repeat", State: "context"}, false},
+		{"repeat bad state", MacroStep{Action: "repeat", State: "on"}, true},
+		{"resume", MacroStep{Action: "play"}, false},
+		{"play context", MacroStep{Action: "play", ContextURI: testPlaylistURI}, false},
+		{"play context and uris", MacroStep{Action: "play", ContextURI: testAlbumURI, URIs: []string{testTrackURI}}, true},
+		{"pause", MacroStep{Action: "pause"}, false},
+		{"next", MacroStep{Action: "next", DeviceID: "d1"}, false},
+		{"previous", MacroStep{Action: "previous"}, false},
+		{"seek", MacroStep{Action: "seek", PositionMS: &zero}, false},
+		{"seek missing", MacroStep{Action: "seek"}, true},
+		{"seek negative", MacroStep{Action: "seek", PositionMS: &negative}, true},
+		{"queue episode", MacroStep{Action: "queue", URI: testEpisodeURI}, false},
+		{"queue album", MacroStep{Action: "queue", URI: testAlbumURI}, true},
+		{"wait", MacroStep{Action: "wait", WaitMS: 500}, false},
+		{"wait longest", MacroStep{Action: "wait", WaitMS: int(maxMacroWait.Milliseconds())}, false},
+		{"wait too long", MacroStep{Action: "wait", WaitMS: int(maxMacroWait.Milliseconds()) + 1}, true},
+		{"wait zero", MacroStep{Action: "wait"}, true},
+		{"wait on a device", MacroStep{Action: "wait", WaitMS: 500, DeviceName: "kitchen"}, true},
+		{"unknown action", MacroStep{Action: "stop"}, true},
+		{"no action", MacroStep{}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.step.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMacrosSet(t *testing.T) {
+	macros, err := NewMacros(nil, nil, filepath.Join(t.TempDir(), "macros.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	half := int(maxMacroWait.Milliseconds()) / 2
+	pause := MacroStep{Action: "pause"}
+	tests := []struct {
+		name         string
+		macroName    string
+		steps        []MacroStep
+		wantStatus   int
+		wantReplaced bool
+	}{
+		{"create", "night", []MacroStep{pause}, http.StatusOK, false},
+		{"replace", "night", []MacroStep{pause, {Action: "wait", WaitMS: half}, {Action: "wait", WaitMS: half}}, http.StatusOK, true},
+		{"bad name", "good night", []MacroStep{pause}, http.StatusBadRequest, false},
+		{"no steps", "empty", nil, http.StatusBadRequest, false},
+		{"too many steps", "long", make([]MacroStep, maxMacroSteps+1), http.StatusBadRequest, false},
+		{"invalid step", "broken", []MacroStep{pause, {Action: "volume"}}, http.StatusBadRequest, false},
+		{"waits add up to too much", "slow", []MacroStep{{Action: "wait", WaitMS: half}, {Action: "wait", WaitMS: half + 1}}, http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		_, replaced, status, err := macros.Set("u", tt.macroName, tt.steps)
+		if status != tt.wantStatus || replaced != tt.wantReplaced || (err != nil) != (tt.wantStatus != http.StatusOK) {
+			t.Errorf("%s: Set() = %v, %d, %v, want %v, %d", tt.name, replaced, status, err, tt.wantReplaced, tt.wantStatus)
+		}
+	}
+	if got := len(macros.List("u")); got != 1 {
+		t.Errorf("List() has %d macros, want 1", got)
+	}
+}
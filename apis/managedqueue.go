@@ -0,0 +1,269 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	managedQueueTick = 3 * time.Second
+	// managedQueueLead is how long before the end of the current item the next one is pushed to Spotify.
+	managedQueueLead = 10 * time.Second
+	maxManagedQueue  = 500
+)
+
+// ManagedQueueItem is a track or episode waiting in a managed queue.
+type ManagedQueueItem struct {
+	ID      string    `json:"id"`
+	URI     string    `json:"uri"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+type managedQueue struct {
+	Items []ManagedQueueItem `json:"items"`
+	// FedDuring is the ID of the item that was playing when the last item was pushed to Spotify, and
+	// FedAtMS its progress at the time. Progress going backwards on the same item means it is playing again.
+	FedDuring string `json:"fed_during,omitempty"`
+	FedAtMS   int64  `json:"fed_at_ms,omitempty"`
+}
+
+// ManagedQueues keeps a queue per user that, unlike Spotify's own queue, can be reordered and cleared.
+// Items stay on the server until the current item is about to finish, then the next one is pushed
+// to Spotify's queue with AddToQueue.
+type ManagedQueues struct {
+	tokenMx *TokenManager
+	path    string
+	queues  map[string]*managedQueue
+	mutx    sync.Mutex
+}
+
+func NewManagedQueues(tokenMx *TokenManager, path string) (*ManagedQueues, error) {
+	mq := &ManagedQueues{tokenMx: tokenMx, path: path, queues: map[string]*managedQueue{}}
+	if err := loadJSON(path, &mq.queues); err != nil {
+		return nil, err
+	}
+	return mq, nil
+}
+
+// Start pushes items to the logged-in user's playback in the background until ctx is cancelled.
+func (mq *ManagedQueues) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(managedQueueTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				mq.feed()
+			}
+		}
+	}()
+}
+
+// List returns the user's queued items in play order.
+func (mq *ManagedQueues) List(userID string) []ManagedQueueItem {
+	mq.mutx.Lock()
+	defer mq.mutx.Unlock()
+
+	if queue := mq.queues[userID]; queue != nil {
+		return slices.Clone(queue.Items)
+	}
+	return []ManagedQueueItem{}
+}
+
+// Insert adds track or episode URIs at position, keeping their order. A negative position appends.
+func (mq *ManagedQueues) Insert(userID string, uris []string, position int) ([]ManagedQueueItem, int, error) {
+	if len(uris) == 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("uris is required")
+	}
+	items := make([]ManagedQueueItem, 0, len(uris))
+	for _, uri := range uris {
+		if err := ValidateSpotifyURI(uri, PlayableURITypes...); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		items = append(items, ManagedQueueItem{ID: newID(), URI: uri, AddedAt: time.Now().UTC()})
+	}
+
+	mq.mutx.Lock()
+	defer mq.mutx.Unlock()
+
+	queue := mq.queue(userID)
+	if len(queue.Items)+len(items) > maxManagedQueue {
+		return nil, http.StatusBadRequest, fmt.Errorf("the queue can hold at most %d items", maxManagedQueue)
+	}
+	if position < 0 || position > len(queue.Items) {
+		position = len(queue.Items)
+	}
+	previous := queue.Items
+	queue.Items = slices.Insert(slices.Clone(queue.Items), position, items...)
+	if err := mq.save(); err != nil {
+		queue.Items = previous
+		return nil, http.StatusInternalServerError, err
+	}
+	return items, http.StatusCreated, nil
+}
+
+// Move puts an item at a new position, counted after it has been taken out. It reports whether the item exists.
+func (mq *ManagedQueues) Move(userID, itemID string, position int) (bool, error) {
+	mq.mutx.Lock()
+	defer mq.mutx.Unlock()
+
+	queue := mq.queue(userID)
+	i := slices.IndexFunc(queue.Items, func(item ManagedQueueItem) bool { return item.ID == itemID })
+	if i < 0 {
+		return false, nil
+	}
+	item := queue.Items[i]
+	previous := queue.Items
+	items := slices.Delete(slices.Clone(queue.Items), i, i+1)
+	if position < 0 || position > len(items) {
+		position = len(items)
+	}
+	queue.Items = slices.Insert(items, position, item)
+	if err := mq.save(); err != nil {
+		queue.Items = previous
+		return true, err
+	}
+	return true, nil
+}
+
+// Remove takes an item out of the queue and reports whether it existed.
+func (mq *ManagedQueues) Remove(userID, itemID string) (bool, error) {
+	mq.mutx.Lock()
+	defer mq.mutx.Unlock()
+
+	queue := mq.queue(userID)
+	i := slices.IndexFunc(queue.Items, func(item ManagedQueueItem) bool { return item.ID == itemID })
+	if i < 0 {
+		return false, nil
+	}
+	previous := queue.Items
+	queue.Items = slices.Delete(slices.Clone(queue.Items), i, i+1)
+	if err := mq.save(); err != nil {
+		queue.Items = previous
+		return false, err
+	}
+	return true, nil
+}
+
+// Clear empties the user's queue. Items already pushed to Spotify stay in Spotify's queue.
+func (mq *ManagedQueues) Clear(userID string) error {
+	mq.mutx.Lock()
+	defer mq.mutx.Unlock()
+
+	queue := mq.queue(userID)
+	previous := queue.Items
+	queue.Items = []ManagedQueueItem{}
+	if err := mq.save(); err != nil {
+		queue.Items = previous
+		return err
+	}
+	return nil
+}
+
+// Play starts the first queued item right away with StartPlayback and takes it off the queue.
+func (mq *ManagedQueues) Play(accessToken, userID, deviceID string) (*ManagedQueueItem, int, error) {
+	mq.mutx.Lock()
+	queue := mq.queue(userID)
+	if len(queue.Items) == 0 {
+		mq.mutx.Unlock()
+		return nil, http.StatusConflict, fmt.Errorf("the queue is empty")
+	}
+	next := queue.Items[0]
+	mq.mutx.Unlock()
+
+	status, err := StartPlayback(accessToken, deviceID, PlaybackOptions{URIs: []string{next.URI}})
+	if err != nil {
+		return nil, status, err
+	}
+
+	mq.mutx.Lock()
+	defer mq.mutx.Unlock()
+
+	queue.FedDuring = ""
+	if err := mq.take(queue, next.ID); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return &next, status, nil
+}
+
+// take removes a played or pushed item, which may have been moved or removed while the lock was released,
+// and saves the queues. Callers hold mq.mutx.
+func (mq *ManagedQueues) take(queue *managedQueue, itemID string) error {
+	if i := slices.IndexFunc(queue.Items, func(item ManagedQueueItem) bool { return item.ID == itemID }); i >= 0 {
+		queue.Items = slices.Delete(slices.Clone(queue.Items), i, i+1)
+	}
+	return mq.save()
+}
+
+// queue returns the user's queue, creating it when needed. Callers hold mq.mutx.
+func (mq *ManagedQueues) queue(userID string) *managedQueue {
+	queue := mq.queues[userID]
+	if queue == nil {
+		queue = &managedQueue{Items: []ManagedQueueItem{}}
+		mq.queues[userID] = queue
+	}
+	return queue
+}
+
+func (mq *ManagedQueues) save() error {
+	return saveJSON(mq.path, mq.queues)
+}
+
+// feed pushes the next item to Spotify's queue once the current item is about to finish.
+func (mq *ManagedQueues) feed() {
+	accessToken, valid := mq.tokenMx.GetToken()
+	userID := mq.tokenMx.GetUser()
+	if !valid || userID == "" {
+		return
+	}
+	mq.mutx.Lock()
+	waiting := mq.queues[userID] != nil && len(mq.queues[userID].Items) > 0
+	mq.mutx.Unlock()
+	if !waiting {
+		return
+	}
+
+	current, _, err := GetCurrentPlayingTrack(accessToken)
+	if err != nil || current.Item == nil || !current.IsPlaying {
+		return
+	}
+	left := time.Duration(int64(current.Item.DurationMS)-current.ProgressMS) * time.Millisecond
+
+	mq.mutx.Lock()
+	queue := mq.queues[userID]
+	if queue == nil || len(queue.Items) == 0 {
+		mq.mutx.Unlock()
+		return
+	}
+	// The same item queued twice in a row starts again from the beginning; it has not been fed for yet.
+	if queue.FedDuring == current.Item.ID && current.ProgressMS < queue.FedAtMS {
+		queue.FedDuring = ""
+	}
+	if left > managedQueueLead || queue.FedDuring == current.Item.ID {
+		mq.mutx.Unlock()
+		return
+	}
+	next := queue.Items[0]
+	mq.mutx.Unlock()
+
+	if _, err := AddToQueue(accessToken, "", next.URI); err != nil {
+		log.Printf("managed queue: %v", err)
+		return
+	}
+
+	mq.mutx.Lock()
+	defer mq.mutx.Unlock()
+
+	queue.FedDuring = current.Item.ID
+	queue.FedAtMS = current.ProgressMS
+	if err := mq.take(queue, next.ID); err != nil {
+		log.Printf("managed queue: %v", err)
+	}
+}
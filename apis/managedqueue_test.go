@@ -0,0 +1,126 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func queueTestURI(n int) string {
+	return fmt.Sprintf("spotify:track:%022d", n)
+}
+
+// queueURIs returns the numbers of the queued test URIs in play order.
+func queueURIs(mq *ManagedQueues, userID string) []int {
+	var ns []int
+	for _, item := range mq.List(userID) {
+		var n int
+		fmt.Sscanf(item.URI, "spotify:track:%d", &n)
+		ns = append(ns, n)
+	}
+	return ns
+}
+
+func newTestManagedQueues(t *testing.T, uris ...int) *ManagedQueues {
+	t.Helper()
+	mq, err := NewManagedQueues(nil, filepath.Join(t.TempDir(), "queues.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var initial []string
+	for _, n := range uris {
+		initial = append(initial, queueTestURI(n))
+	}
+	if len(initial) > 0 {
+		if _, _, err := mq.Insert("u", initial, -1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return mq
+}
+
+func TestManagedQueueInsert(t *testing.T) {
+	tests := []struct {
+		name       string
+		uris       []string
+		position   int
+		want       []int
+		wantStatus int
+	}{
+		{"append", []string{queueTestURI(4), queueTestURI(5)}, -1, []int{1, 2, 3, 4, 5}, http.StatusCreated},
+		{"front", []string{queueTestURI(4)}, 0, []int{4, 1, 2, 3}, http.StatusCreated},
+		{"middle keeps order", []string{queueTestURI(4), queueTestURI(5)}, 1, []int{1, 4, 5, 2, 3}, http.StatusCreated},
+		{"at the end", []string{queueTestURI(4)}, 3, []int{1, 2, 3, 4}, http.StatusCreated},
+		{"past the end appends", []string{queueTestURI(4)}, 99, []int{1, 2, 3, 4}, http.StatusCreated},
+		{"episode", []string{"spotify:episode:512ojhOuo1ktJprKbVcKyQ"}, -1, []int{1, 2, 3, 0}, http.StatusCreated},
+		{"nothing to add", nil, -1, []int{1, 2, 3}, http.StatusBadRequest},
+		{"album is not playable", []string{queueTestURI(4), "spotify:album:4aawyAB9vmqN3uQ7FjRGTy"}, -1, []int{1, 2, 3}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mq := newTestManagedQueues(t, 1, 2, 3)
+			items, status, err := mq.Insert("u", tt.uris, tt.position)
+			if status != tt.wantStatus || (err != nil) != (tt.wantStatus != http.StatusCreated) {
+				t.Fatalf("Insert() = %d, %v, want %d", status, err, tt.wantStatus)
+			}
+			if err == nil && len(items) != len(tt.uris) {
+				t.Errorf("Insert() returned %d items, want %d", len(items), len(tt.uris))
+			}
+			if got := queueURIs(mq, "u"); !slices.Equal(got, tt.want) {
+				t.Errorf("queue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManagedQueueInsertLimit(t *testing.T) {
+	mq := newTestManagedQueues(t)
+	uris := make([]string, maxManagedQueue)
+	for i := range uris {
+		uris[i] = queueTestURI(i)
+	}
+	if _, _, err := mq.Insert("u", uris, -1); err != nil {
+		t.Fatal(err)
+	}
+	if _, status, err := mq.Insert("u", []string{queueTestURI(1)}, -1); status != http.StatusBadRequest || err == nil {
+		t.Errorf("Insert() into a full queue = %d, %v, want %d", status, err, http.StatusBadRequest)
+	}
+	if n := len(mq.List("other")); n != 0 {
+		t.Errorf("another user's queue has %d items, want 0", n)
+	}
+}
+
+func TestManagedQueueMove(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      int
+		position  int
+		want      []int
+		wantFound bool
+	}{
+		{"to the front", 2, 0, []int{3, 1, 2, 4}, true},
+		{"to the end", 0, 3, []int{2, 3, 4, 1}, true},
+		{"past the end", 1, 99, []int{1, 3, 4, 2}, true},
+		{"negative goes to the end", 0, -1, []int{2, 3, 4, 1}, true},
+		{"same place", 1, 1, []int{1, 2, 3, 4}, true},
+		{"unknown item", -1, 0, []int{1, 2, 3, 4}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mq := newTestManagedQueues(t, 1, 2, 3, 4)
+			itemID := "missing"
+			if tt.from >= 0 {
+				itemID = mq.List("u")[tt.from].ID
+			}
+			found, err := mq.Move("u", itemID, tt.position)
+			if found != tt.wantFound || err != nil {
+				t.Fatalf("Move() = %v, %v, want %v, nil", found, err, tt.wantFound)
+			}
+			if got := queueURIs(mq, "u"); !slices.Equal(got, tt.want) {
+				t.Errorf("queue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+)
+
+// Paging is Spotify's offset-based paging object.
+type Paging[T any] struct {
+	Href     string `json:"href"`
+	Items    []T    `json:"items"`
+	Limit    int    `json:"limit"`
+	Next     string `json:"next"`
+	Offset   int    `json:"offset"`
+	Previous string `json:"previous"`
+	Total    int    `json:"total"`
+}
+
+type Cursors struct {
+	After  string `json:"after"`
+	Before string `json:"before"`
+}
+
+// CursorPaging is Spotify's cursor-based paging object, used by endpoints such as recently played.
+type CursorPaging[T any] struct {
+	Href    string   `json:"href"`
+	Items   []T      `json:"items"`
+	Limit   int      `json:"limit"`
+	Next    string   `json:"next"`
+	Cursors *Cursors `json:"cursors"`
+	Total   int      `json:"total"`
+}
+
+func (p *Paging[T]) pageItems() []T        { return p.Items }
+func (p *Paging[T]) nextURL() string       { return p.Next }
+func (p *CursorPaging[T]) pageItems() []T  { return p.Items }
+func (p *CursorPaging[T]) nextURL() string { return p.Next }
+
+type page[T any] interface {
+	pageItems() []T
+	nextURL() string
+}
+
+// PageError is yielded by the page iterators when fetching a page fails.
+// StatusCode holds the HTTP status to report back to the client.
+type PageError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *PageError) Error() string { return e.Err.Error() }
+func (e *PageError) Unwrap() error { return e.Err }
+
+// ErrorStatus returns the HTTP status carried by a PageError, or 500 for any other error.
+func ErrorStatus(err error) int {
+	var pageErr *PageError
+	if errors.As(err, &pageErr) {
+		return pageErr.StatusCode
+	}
+	return 500
+}
+
+// getJSON performs an authenticated GET on a full Spotify URL, such as a paging object's next link,
+// and decodes the response into out.
+func getJSON(accessToken, requestURL, action string, out interface{}) (int, error) {
+	req, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return 500, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 500, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, fmt.Errorf("failed to get %s: %s", action, body)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// walkItems lazily fetches firstURL and every page after it by following next links, yielding each item in turn.
+// Iteration stops after the first error, which is yielded as a *PageError.
+func walkItems[T any, P page[T]](firstURL string, fetch func(pageURL string) (P, int, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		pageURL := firstURL
+		for pageURL != "" {
+			current, status, err := fetch(pageURL)
+			if err != nil {
+				var zero T
+				yield(zero, &PageError{StatusCode: status, Err: err})
+				return
+			}
+			for _, item := range current.pageItems() {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			pageURL = current.nextURL()
+		}
+	}
+}
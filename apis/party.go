@@ -0,0 +1,587 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	partyTick = 3 * time.Second
+	// partyFeedLead is how long before the end of the current track the top proposal is queued.
+	partyFeedLead = 15 * time.Second
+	// partyPlayedLimit caps the history of fed proposals kept per room.
+	partyPlayedLimit = 50
+	partyCodeLength  = 6
+	// partyCodeAlphabet leaves out characters that are easy to confuse when read out loud.
+	partyCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// PartyGuest is someone who joined a room by its code. Guests do not need a Spotify account;
+// they are identified by the token handed out when they join.
+type PartyGuest struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// PartyProposal is a track proposed by a guest, with each guest's vote of +1 or -1.
+type PartyProposal struct {
+	ID         string         `json:"id"`
+	Track      *Track         `json:"track"`
+	GuestID    string         `json:"guest_id"`
+	ProposedAt time.Time      `json:"proposed_at"`
+	Votes      map[string]int `json:"votes"`
+	Score      int            `json:"score"`
+	QueuedAt   *time.Time     `json:"queued_at,omitempty"`
+}
+
+// PartyRoom is a shared listening session. Proposals are ranked by score, oldest first on a tie,
+// and the top one is queued on the host's playback as the current track nears its end.
+type PartyRoom struct {
+	Code        string                 `json:"code"`
+	HostUserID  string                 `json:"host_user_id"`
+	CreatedAt   time.Time              `json:"created_at"`
+	MaxPerGuest int                    `json:"max_per_guest"`
+	Guests      map[string]*PartyGuest `json:"guests"`
+	GuestTokens map[string]string      `json:"guest_tokens"`
+	Banned      map[string]bool        `json:"banned"`
+	Proposals   []*PartyProposal       `json:"proposals"`
+	Played      []*PartyProposal       `json:"played"`
+	// FedDuring is the ID of the track that was playing when a proposal was last queued.
+	FedDuring string `json:"fed_during,omitempty"`
+	// RetiredCodes are earlier codes of the room. Guests who already joined can keep using them,
+	// but nobody can join with them.
+	RetiredCodes []string `json:"retired_codes,omitempty"`
+}
+
+// PartyView is what hosts and guests see of a room: the ranked queue without guest tokens.
+type PartyView struct {
+	Code        string           `json:"code"`
+	CreatedAt   time.Time        `json:"created_at"`
+	MaxPerGuest int              `json:"max_per_guest"`
+	Guests      []PartyGuest     `json:"guests"`
+	Queue       []*PartyProposal `json:"queue"`
+	Played      []*PartyProposal `json:"played"`
+}
+
+func (r *PartyRoom) view() *PartyView {
+	view := &PartyView{
+		Code:        r.Code,
+		CreatedAt:   r.CreatedAt,
+		MaxPerGuest: r.MaxPerGuest,
+		Guests:      []PartyGuest{},
+		Queue:       r.ranked(),
+		Played:      slices.Clone(r.Played),
+	}
+	for _, guest := range r.Guests {
+		view.Guests = append(view.Guests, *guest)
+	}
+	slices.SortFunc(view.Guests, func(a, b PartyGuest) int { return a.JoinedAt.Compare(b.JoinedAt) })
+	return view
+}
+
+// ranked returns the pending proposals, best first.
+func (r *PartyRoom) ranked() []*PartyProposal {
+	ranked := slices.Clone(r.Proposals)
+	slices.SortStableFunc(ranked, func(a, b *PartyProposal) int {
+		if a.Score != b.Score {
+			return b.Score - a.Score
+		}
+		return a.ProposedAt.Compare(b.ProposedAt)
+	})
+	return ranked
+}
+
+// canPropose checks that track is not already waiting and that the guest is below the per-guest cap.
+func (r *PartyRoom) canPropose(guestID string, track *Track) (int, error) {
+	pending := 0
+	for _, proposal := range r.Proposals {
+		if proposal.Track.URI == track.URI {
+			return http.StatusConflict, fmt.Errorf("%q has already been proposed", track.Name)
+		}
+		if proposal.GuestID == guestID {
+			pending++
+		}
+	}
+	if r.MaxPerGuest > 0 && pending >= r.MaxPerGuest {
+		return http.StatusTooManyRequests, fmt.Errorf("you can have at most %d tracks waiting", r.MaxPerGuest)
+	}
+	return http.StatusOK, nil
+}
+
+func (r *PartyRoom) proposal(id string) (int, *PartyProposal) {
+	for i, proposal := range r.Proposals {
+		if proposal.ID == id {
+			return i, proposal
+		}
+	}
+	return -1, nil
+}
+
+// PartyManager keeps the party rooms, persists them in a JSON file and feeds the logged-in host's
+// playback from their room. Each host has at most one open room.
+type PartyManager struct {
+	tokenMx *TokenManager
+	path    string
+	rooms   map[string]*PartyRoom
+	mutx    sync.Mutex
+}
+
+func NewPartyManager(tokenMx *TokenManager, path string) (*PartyManager, error) {
+	pm := &PartyManager{tokenMx: tokenMx, path: path, rooms: map[string]*PartyRoom{}}
+	var rooms []*PartyRoom
+	if err := loadJSON(path, &rooms); err != nil {
+		return nil, err
+	}
+	for _, room := range rooms {
+		pm.rooms[room.Code] = room
+	}
+	return pm, nil
+}
+
+// Start feeds the host's playback in the background until ctx is cancelled.
+func (pm *PartyManager) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(partyTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pm.feed()
+			}
+		}
+	}()
+}
+
+// Create opens a room for the host. maxPerGuest caps how many pending proposals a guest may have, 0 meaning no cap.
+func (pm *PartyManager) Create(hostUserID string, maxPerGuest int) (*PartyView, int, error) {
+	if maxPerGuest < 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("max_per_guest must not be negative")
+	}
+	pm.mutx.Lock()
+	defer pm.mutx.Unlock()
+
+	if room := pm.hostRoom(hostUserID); room != nil {
+		return nil, http.StatusConflict, fmt.Errorf("party %s is still open", room.Code)
+	}
+	code := pm.newCode()
+	room := &PartyRoom{
+		Code:        code,
+		HostUserID:  hostUserID,
+		CreatedAt:   time.Now().UTC(),
+		MaxPerGuest: maxPerGuest,
+		Guests:      map[string]*PartyGuest{},
+		GuestTokens: map[string]string{},
+		Banned:      map[string]bool{},
+		Proposals:   []*PartyProposal{},
+		Played:      []*PartyProposal{},
+	}
+	pm.rooms[code] = room
+	if err := pm.save(); err != nil {
+		delete(pm.rooms, code)
+		return nil, http.StatusInternalServerError, err
+	}
+	return room.view(), http.StatusCreated, nil
+}
+
+// Current returns the host's open room.
+func (pm *PartyManager) Current(hostUserID string) (*PartyView, bool) {
+	pm.mutx.Lock()
+	defer pm.mutx.Unlock()
+
+	room := pm.hostRoom(hostUserID)
+	if room == nil {
+		return nil, false
+	}
+	return room.view(), true
+}
+
+// View returns the room to one of its guests.
+func (pm *PartyManager) View(code, guestToken string) (*PartyView, int, error) {
+	pm.mutx.Lock()
+	defer pm.mutx.Unlock()
+
+	room, _, status, err := pm.guestRoom(code, guestToken)
+	if err != nil {
+		return nil, status, err
+	}
+	return room.view(), http.StatusOK, nil
+}
+
+// Close ends the host's room.
+func (pm *PartyManager) Close(hostUserID string) (bool, error) {
+	pm.mutx.Lock()
+	defer pm.mutx.Unlock()
+
+	room := pm.hostRoom(hostUserID)
+	if room == nil {
+		return false, nil
+	}
+	delete(pm.rooms, room.Code)
+	if err := pm.save(); err != nil {
+		pm.rooms[room.Code] = room
+		return false, err
+	}
+	return true, nil
+}
+
+// Join adds a guest to the room and returns the guest with the token that identifies them from now on.
+func (pm *PartyManager) Join(code, name string) (*PartyGuest, string, int, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 40 {
+		return nil, "", http.StatusBadRequest, fmt.Errorf("name must be between 1 and 40 characters")
+	}
+	pm.mutx.Lock()
+	defer pm.mutx.Unlock()
+
+	room, ok := pm.rooms[strings.ToUpper(code)]
+	if !ok {
+		return nil, "", http.StatusNotFound, fmt.Errorf("no party with code %q", code)
+	}
+	guest := &PartyGuest{ID: newID(), Name: name, JoinedAt: time.Now().UTC()}
+	token := newID() + newID()
+	room.Guests[guest.ID] = guest
+	room.GuestTokens[token] = guest.ID
+	if err := pm.save(); err != nil {
+		delete(room.Guests, guest.ID)
+		delete(room.GuestTokens, token)
+		return nil, "", http.StatusInternalServerError, err
+	}
+	return guest, token, http.StatusCreated, nil
+}
+
+// Search runs a track search for a guest with the host's Spotify account.
+func (pm *PartyManager) Search(code, guestToken, query string, limit, offset int32) (*SearchResponse, int, error) {
+	pm.mutx.Lock()
+	_, _, status, err := pm.guestRoom(code, guestToken)
+	pm.mutx.Unlock()
+	if err != nil {
+		return nil, status, err
+	}
+	accessToken, status, err := pm.hostToken(code)
+	if err != nil {
+		return nil, status, err
+	}
+	results, err := SearchSpotify(accessToken, query, "track", limit, offset)
+	if err != nil {
+		return nil, http.StatusBadGateway, err
+	}
+	return results, http.StatusOK, nil
+}
+
+// Propose adds a track to the room's queue on behalf of a guest, counting as the guest's upvote.
+func (pm *PartyManager) Propose(code, guestToken, trackURI string) (*PartyProposal, int, error) {
+	if err := ValidateSpotifyURI(trackURI, "track"); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	// Only guests may spend the host's Spotify calls, so check the token before fetching the track.
+	pm.mutx.Lock()
+	_, _, status, err := pm.guestRoom(code, guestToken)
+	pm.mutx.Unlock()
+	if err != nil {
+		return nil, status, err
+	}
+	_, trackID, _ := ParseSpotifyURI(trackURI)
+	accessToken, status, err := pm.hostToken(code)
+	if err != nil {
+		return nil, status, err
+	}
+	track, status, err := GetTrack(accessToken, trackID)
+	if err != nil {
+		return nil, status, err
+	}
+
+	pm.mutx.Lock()
+	defer pm.mutx.Unlock()
+
+	room, guestID, status, err := pm.guestRoom(code, guestToken)
+	if err != nil {
+		return nil, status, err
+	}
+	if status, err := room.canPropose(guestID, track); err != nil {
+		return nil, status, err
+	}
+	proposal := &PartyProposal{
+		ID:         newID(),
+		Track:      track,
+		GuestID:    guestID,
+		ProposedAt: time.Now().UTC(),
+		Votes:      map[string]int{guestID: 1},
+		Score:      1,
+	}
+	room.Proposals = append(room.Proposals, proposal)
+	if err := pm.save(); err != nil {
+		room.Proposals = room.Proposals[:len(room.Proposals)-1]
+		return nil, http.StatusInternalServerError, err
+	}
+	return proposal, http.StatusCreated, nil
+}
+
+// Vote records a guest's vote on a proposal: 1 for up, -1 for down and 0 to take the vote back.
+func (pm *PartyManager) Vote(code, guestToken, proposalID string, vote int) (*PartyProposal, int, error) {
+	if vote < -1 || vote > 1 {
+		return nil, http.StatusBadRequest, fmt.Errorf("vote must be 1, -1 or 0")
+	}
+	pm.mutx.Lock()
+	defer pm.mutx.Unlock()
+
+	room, guestID, status, err := pm.guestRoom(code, guestToken)
+	if err != nil {
+		return nil, status, err
+	}
+	_, proposal := room.proposal(proposalID)
+	if proposal == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("proposal not found")
+	}
+	previous, voted := proposal.Votes[guestID]
+	proposal.Score += vote - previous
+	if vote == 0 {
+		delete(proposal.Votes, guestID)
+	} else {
+		proposal.Votes[guestID] = vote
+	}
+	if err := pm.save(); err != nil {
+		proposal.Score -= vote - previous
+		if voted {
+			proposal.Votes[guestID] = previous
+		} else {
+			delete(proposal.Votes, guestID)
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+	return proposal, http.StatusOK, nil
+}
+
+// Remove takes a proposal out of the host's room.
+func (pm *PartyManager) Remove(hostUserID, proposalID string) (bool, error) {
+	pm.mutx.Lock()
+	defer pm.mutx.Unlock()
+
+	room := pm.hostRoom(hostUserID)
+	if room == nil {
+		return false, nil
+	}
+	i, proposal := room.proposal(proposalID)
+	if proposal == nil {
+		return false, nil
+	}
+	room.Proposals = slices.Delete(room.Proposals, i, i+1)
+	if err := pm.save(); err != nil {
+		room.Proposals = slices.Insert(room.Proposals, i, proposal)
+		return false, err
+	}
+	return true, nil
+}
+
+// Ban removes a guest's proposals and votes from the host's room and locks out their token.
+// Guests have no accounts, so the room also gets a new code, returned here, to keep the banned guest
+// from joining again under a new name. Guests who already joined keep access through the old code.
+func (pm *PartyManager) Ban(hostUserID, guestID string) (string, bool, error) {
+	pm.mutx.Lock()
+	defer pm.mutx.Unlock()
+
+	room := pm.hostRoom(hostUserID)
+	if room == nil || room.Guests[guestID] == nil {
+		return "", false, nil
+	}
+	proposals, votes := room.Proposals, map[*PartyProposal]int{}
+	oldCode, retired := room.Code, room.RetiredCodes
+	room.Banned[guestID] = true
+	room.Proposals = slices.DeleteFunc(slices.Clone(room.Proposals), func(p *PartyProposal) bool { return p.GuestID == guestID })
+	for _, proposal := range room.Proposals {
+		if vote, ok := proposal.Votes[guestID]; ok {
+			votes[proposal] = vote
+			proposal.Score -= vote
+			delete(proposal.Votes, guestID)
+		}
+	}
+	room.Code = pm.newCode()
+	room.RetiredCodes = append(slices.Clone(retired), oldCode)
+	delete(pm.rooms, oldCode)
+	pm.rooms[room.Code] = room
+	if err := pm.save(); err != nil {
+		delete(pm.rooms, room.Code)
+		room.Code, room.RetiredCodes = oldCode, retired
+		pm.rooms[oldCode] = room
+		for proposal, vote := range votes {
+			proposal.Score += vote
+			proposal.Votes[guestID] = vote
+		}
+		room.Proposals = proposals
+		delete(room.Banned, guestID)
+		return "", false, err
+	}
+	return room.Code, true, nil
+}
+
+// SetMaxPerGuest changes the cap on pending proposals per guest in the host's room.
+func (pm *PartyManager) SetMaxPerGuest(hostUserID string, maxPerGuest int) (int, error) {
+	if maxPerGuest < 0 {
+		return http.StatusBadRequest, fmt.Errorf("max_per_guest must not be negative")
+	}
+	pm.mutx.Lock()
+	defer pm.mutx.Unlock()
+
+	room := pm.hostRoom(hostUserID)
+	if room == nil {
+		return http.StatusNotFound, fmt.Errorf("no party is open")
+	}
+	previous := room.MaxPerGuest
+	room.MaxPerGuest = maxPerGuest
+	if err := pm.save(); err != nil {
+		room.MaxPerGuest = previous
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// hostRoom returns the host's open room. Callers hold pm.mutx.
+func (pm *PartyManager) hostRoom(hostUserID string) *PartyRoom {
+	for _, room := range pm.rooms {
+		if room.HostUserID == hostUserID {
+			return room
+		}
+	}
+	return nil
+}
+
+// room returns the room with code as its current or a retired code. Callers hold pm.mutx.
+func (pm *PartyManager) room(code string) (*PartyRoom, bool) {
+	code = strings.ToUpper(code)
+	if room, ok := pm.rooms[code]; ok {
+		return room, true
+	}
+	for _, room := range pm.rooms {
+		if slices.Contains(room.RetiredCodes, code) {
+			return room, true
+		}
+	}
+	return nil, false
+}
+
+// newCode returns a code that no room uses, now or before. Callers hold pm.mutx.
+func (pm *PartyManager) newCode() string {
+	code := newPartyCode()
+	for _, taken := pm.room(code); taken; _, taken = pm.room(code) {
+		code = newPartyCode()
+	}
+	return code
+}
+
+// guestRoom returns the room and the ID of the guest holding guestToken. Callers hold pm.mutx.
+func (pm *PartyManager) guestRoom(code, guestToken string) (*PartyRoom, string, int, error) {
+	room, ok := pm.room(code)
+	if !ok {
+		return nil, "", http.StatusNotFound, fmt.Errorf("no party with code %q", code)
+	}
+	guestID, ok := room.GuestTokens[guestToken]
+	if !ok || guestToken == "" {
+		return nil, "", http.StatusUnauthorized, fmt.Errorf("join the party first")
+	}
+	if room.Banned[guestID] {
+		return nil, "", http.StatusForbidden, fmt.Errorf("you have been removed from this party")
+	}
+	return room, guestID, http.StatusOK, nil
+}
+
+// hostToken returns the access token of the room's host, who must be the logged-in user.
+func (pm *PartyManager) hostToken(code string) (string, int, error) {
+	pm.mutx.Lock()
+	room, ok := pm.room(code)
+	pm.mutx.Unlock()
+	if !ok {
+		return "", http.StatusNotFound, fmt.Errorf("no party with code %q", code)
+	}
+	accessToken, valid := pm.tokenMx.GetToken()
+	if !valid || pm.tokenMx.GetUser() != room.HostUserID {
+		return "", http.StatusServiceUnavailable, fmt.Errorf("the host is not connected to Spotify")
+	}
+	return accessToken, http.StatusOK, nil
+}
+
+func (pm *PartyManager) save() error {
+	rooms := make([]*PartyRoom, 0, len(pm.rooms))
+	for _, room := range pm.rooms {
+		rooms = append(rooms, room)
+	}
+	slices.SortFunc(rooms, func(a, b *PartyRoom) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return saveJSON(pm.path, rooms)
+}
+
+// feed queues the top proposal of the logged-in host's room once the current track is about to end.
+func (pm *PartyManager) feed() {
+	accessToken, valid := pm.tokenMx.GetToken()
+	userID := pm.tokenMx.GetUser()
+	if !valid || userID == "" {
+		return
+	}
+	pm.mutx.Lock()
+	room := pm.hostRoom(userID)
+	waiting := room != nil && len(room.Proposals) > 0
+	pm.mutx.Unlock()
+	if !waiting {
+		return
+	}
+
+	current, _, err := GetCurrentPlayingTrack(accessToken)
+	if err != nil || current.Item == nil || !current.IsPlaying {
+		return
+	}
+	left := time.Duration(int64(current.Item.DurationMS)-current.ProgressMS) * time.Millisecond
+	if left > partyFeedLead {
+		return
+	}
+
+	pm.mutx.Lock()
+	if pm.hostRoom(userID) != room || room.FedDuring == current.Item.ID || len(room.Proposals) == 0 {
+		pm.mutx.Unlock()
+		return
+	}
+	next := room.ranked()[0]
+	code, proposalID, uri := room.Code, next.ID, next.Track.URI
+	pm.mutx.Unlock()
+
+	if _, err := AddToQueue(accessToken, "", uri); err != nil {
+		log.Printf("party %s: %v", code, err)
+		return
+	}
+
+	pm.mutx.Lock()
+	defer pm.mutx.Unlock()
+
+	// The room may have been closed, and the proposal removed, while the track was being queued.
+	if pm.hostRoom(userID) != room {
+		return
+	}
+	if i, proposal := room.proposal(proposalID); proposal != nil {
+		now := time.Now().UTC()
+		proposal.QueuedAt = &now
+		room.Proposals = slices.Delete(room.Proposals, i, i+1)
+		room.Played = append(room.Played, proposal)
+		if len(room.Played) > partyPlayedLimit {
+			room.Played = room.Played[len(room.Played)-partyPlayedLimit:]
+		}
+	}
+	room.FedDuring = current.Item.ID
+	if err := pm.save(); err != nil {
+		log.Printf("party %s: %v", room.Code, err)
+	}
+}
+
+func newPartyCode() string {
+	buf := make([]byte, partyCodeLength)
+	rand.Read(buf)
+	for i, b := range buf {
+		buf[i] = partyCodeAlphabet[int(b)%len(partyCodeAlphabet)]
+	}
+	return string(buf)
+}
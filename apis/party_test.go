@@ -0,0 +1,208 @@
+package api
+
+import (
+	"net/http"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestParty(t *testing.T) (*PartyManager, *PartyRoom) {
+	t.Helper()
+	pm, err := NewPartyManager(nil, filepath.Join(t.TempDir(), "parties.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	view, _, err := pm.Create("host", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pm, pm.rooms[view.Code]
+}
+
+func joinTestParty(t *testing.T, pm *PartyManager, code, name string) (*PartyGuest, string) {
+	t.Helper()
+	guest, token, _, err := pm.Join(code, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return guest, token
+}
+
+func addTestProposal(room *PartyRoom, id, guestID, uri string, score int, proposedAt time.Time) *PartyProposal {
+	proposal := &PartyProposal{
+		ID:         id,
+		Track:      &Track{URI: uri, Name: id},
+		GuestID:    guestID,
+		ProposedAt: proposedAt,
+		Votes:      map[string]int{guestID: 1},
+		Score:      score,
+	}
+	room.Proposals = append(room.Proposals, proposal)
+	return proposal
+}
+
+func proposalIDs(proposals []*PartyProposal) []string {
+	var ids []string
+	for _, proposal := range proposals {
+		ids = append(ids, proposal.ID)
+	}
+	return ids
+}
+
+func TestPartyRanked(t *testing.T) {
+	start := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	room := &PartyRoom{}
+	addTestProposal(room, "a", "g1", "spotify:track:a", 1, start)
+	addTestProposal(room, "b", "g1", "spotify:track:b", 3, start.Add(time.Minute))
+	addTestProposal(room, "c", "g2", "spotify:track:c", 1, start.Add(-time.Minute))
+	addTestProposal(room, "d", "g2", "spotify:track:d", -2, start.Add(-time.Hour))
+
+	want := []string{"b", "c", "a", "d"}
+	if got := proposalIDs(room.ranked()); !slices.Equal(got, want) {
+		t.Errorf("ranked() = %v, want %v", got, want)
+	}
+	if got := proposalIDs(room.Proposals); !slices.Equal(got, []string{"a", "b", "c", "d"}) {
+		t.Errorf("ranked() reordered the proposals to %v", got)
+	}
+}
+
+func TestPartyCanPropose(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name        string
+		maxPerGuest int
+		uri         string
+		wantStatus  int
+	}{
+		{"new track without a cap", 0, "spotify:track:new", http.StatusOK},
+		{"already proposed", 0, "spotify:track:b", http.StatusConflict},
+		{"below the cap", 3, "spotify:track:new", http.StatusOK},
+		{"at the cap", 2, "spotify:track:new", http.StatusTooManyRequests},
+		{"duplicate is reported before the cap", 2, "spotify:track:c", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := &PartyRoom{MaxPerGuest: tt.maxPerGuest}
+			addTestProposal(room, "a", "g1", "spotify:track:a", 1, now)
+			addTestProposal(room, "b", "g1", "spotify:track:b", 1, now)
+			addTestProposal(room, "c", "g2", "spotify:track:c", 1, now)
+			status, err := room.canPropose("g1", &Track{URI: tt.uri})
+			if status != tt.wantStatus || (err != nil) != (tt.wantStatus != http.StatusOK) {
+				t.Errorf("canPropose() = %d, %v, want %d", status, err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestPartyVote(t *testing.T) {
+	pm, room := newTestParty(t)
+	owner, _ := joinTestParty(t, pm, room.Code, "owner")
+	_, token := joinTestParty(t, pm, room.Code, "voter")
+	proposal := addTestProposal(room, "p", owner.ID, "spotify:track:p", 1, time.Now())
+
+	tests := []struct {
+		name       string
+		token      string
+		proposalID string
+		vote       int
+		wantStatus int
+		wantScore  int
+	}{
+		{"upvote", token, "p", 1, http.StatusOK, 2},
+		{"upvote again", token, "p", 1, http.StatusOK, 2},
+		{"switch to a downvote", token, "p", -1, http.StatusOK, 0},
+		{"withdraw", token, "p", 0, http.StatusOK, 1},
+		{"out of range", token, "p", 2, http.StatusBadRequest, 1},
+		{"unknown proposal", token, "missing", 1, http.StatusNotFound, 1},
+		{"without joining", "", "p", 1, http.StatusUnauthorized, 1},
+	}
+	for _, tt := range tests {
+		_, status, err := pm.Vote(room.Code, tt.token, tt.proposalID, tt.vote)
+		if status != tt.wantStatus || (err != nil) != (tt.wantStatus != http.StatusOK) {
+			t.Errorf("%s: Vote() = %d, %v, want %d", tt.name, status, err, tt.wantStatus)
+		}
+		if proposal.Score != tt.wantScore {
+			t.Errorf("%s: score = %d, want %d", tt.name, proposal.Score, tt.wantScore)
+		}
+	}
+	if _, voted := proposal.Votes[room.GuestTokens[token]]; voted {
+		t.Errorf("withdrawn vote is still recorded: %v", proposal.Votes)
+	}
+}
+
+func TestPartyBan(t *testing.T) {
+	pm, room := newTestParty(t)
+	oldCode := room.Code
+	banned, bannedToken := joinTestParty(t, pm, oldCode, "banned")
+	guest, guestToken := joinTestParty(t, pm, oldCode, "guest")
+	addTestProposal(room, "theirs", banned.ID, "spotify:track:theirs", 1, time.Now())
+	kept := addTestProposal(room, "kept", guest.ID, "spotify:track:kept", 1, time.Now())
+	if _, _, err := pm.Vote(oldCode, bannedToken, "kept", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found, err := pm.Ban("host", "nobody"); found || err != nil {
+		t.Errorf("Ban(unknown guest) = %v, %v, want false, nil", found, err)
+	}
+	code, found, err := pm.Ban("host", banned.ID)
+	if !found || err != nil {
+		t.Fatalf("Ban() = %v, %v, want true, nil", found, err)
+	}
+	if code == oldCode || room.Code != code || !slices.Equal(room.RetiredCodes, []string{oldCode}) {
+		t.Errorf("codes after ban = %q, retired %v, want a new code with %q retired", room.Code, room.RetiredCodes, oldCode)
+	}
+	if got := proposalIDs(room.Proposals); !slices.Equal(got, []string{"kept"}) {
+		t.Errorf("proposals after ban = %v, want [kept]", got)
+	}
+	if _, voted := kept.Votes[banned.ID]; voted || kept.Score != 1 {
+		t.Errorf("banned guest's vote is still counted: score %d, votes %v", kept.Score, kept.Votes)
+	}
+
+	if _, status, _ := pm.View(oldCode, bannedToken); status != http.StatusForbidden {
+		t.Errorf("banned guest View() = %d, want %d", status, http.StatusForbidden)
+	}
+	if _, _, status, _ := pm.Join(oldCode, "banned again"); status != http.StatusNotFound {
+		t.Errorf("Join(retired code) = %d, want %d", status, http.StatusNotFound)
+	}
+	if _, _, status, _ := pm.Join(code, "newcomer"); status != http.StatusCreated {
+		t.Errorf("Join(new code) = %d, want %d", status, http.StatusCreated)
+	}
+
+	reloaded, err := NewPartyManager(nil, pm.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pm := range []*PartyManager{pm, reloaded} {
+		for _, c := range []string{oldCode, code} {
+			if _, status, err := pm.View(c, guestToken); status != http.StatusOK {
+				t.Errorf("guest View(%q) = %d, %v, want %d", c, status, err, http.StatusOK)
+			}
+		}
+	}
+}
+
+func TestPartySetMaxPerGuest(t *testing.T) {
+	pm, room := newTestParty(t)
+	tests := []struct {
+		host        string
+		maxPerGuest int
+		wantStatus  int
+		wantMax     int
+	}{
+		{"host", 3, http.StatusOK, 3},
+		{"host", -1, http.StatusBadRequest, 3},
+		{"stranger", 5, http.StatusNotFound, 3},
+		{"host", 0, http.StatusOK, 0},
+	}
+	for _, tt := range tests {
+		status, err := pm.SetMaxPerGuest(tt.host, tt.maxPerGuest)
+		if status != tt.wantStatus || (err != nil) != (tt.wantStatus != http.StatusOK) {
+			t.Errorf("SetMaxPerGuest(%q, %d) = %d, %v, want %d", tt.host, tt.maxPerGuest, status, err, tt.wantStatus)
+		}
+		if room.MaxPerGuest != tt.wantMax {
+			t.Errorf("SetMaxPerGuest(%q, %d) left the cap at %d, want %d", tt.host, tt.maxPerGuest, room.MaxPerGuest, tt.wantMax)
+		}
+	}
+}
@@ -5,7 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
 )
 
 type Actions struct {
@@ -27,11 +32,25 @@ type PlayBackResponse struct {
 	ShuffleState bool `json:"shuffle_state"`
 	Timestamp uint64 `json:"timestamp"`
 	ProgressMS uint64 `json:"progress_ms"`
+	IsPlaying bool `json:"is_playing"`
 	Item *Track `json:"item"`
+	Context *Context `json:"context"`
 	CurrentlyPlayingType string `json:"currently_playing_type"`
 	Actions *Actions `json:"actions"`
 }
 
+// playerURL builds a /me/player URL with params encoded, adding device_id when one is given.
+func playerURL(endpoint string, params url.Values, deviceID string) string {
+	if deviceID != "" {
+		params.Set("device_id", deviceID)
+	}
+	playerURL := BaseAPIURL + "/me/player" + endpoint
+	if len(params) > 0 {
+		playerURL += "?" + params.Encode()
+	}
+	return playerURL
+}
+
 func GetPlayBack(accessToken string) (*PlayBackResponse, int, error) {
 	playbackURL := fmt.Sprintf("%s/me/player", BaseAPIURL)
 	req, err := http.NewRequest("GET", playbackURL, nil)
@@ -182,27 +201,84 @@ func GetCurrentPlayingTrack(accessToken string) (*CurrentTrackResponse, int, err
 	return &results, resp.StatusCode, nil
 }
 
-func StartPlayback(accessToken, deviceID, contextURI string, offsetPosition, positionMS int) (int, error) {
-	trackURL := fmt.Sprintf("%s/me/player/play?device_id=%s", BaseAPIURL, deviceID)
+// PlaybackOffset selects where in a context or uris list playback starts, either by position or by item URI.
+type PlaybackOffset struct {
+	Position *int `json:"position,omitempty"`
+	URI string `json:"uri,omitempty"`
+}
+
+// PlaybackOptions is the body of a start playback request. Fields left empty are not sent,
+// so the zero value resumes whatever was playing.
+type PlaybackOptions struct {
+	ContextURI string `json:"context_uri,omitempty"`
+	URIs []string `json:"uris,omitempty"`
+	Offset *PlaybackOffset `json:"offset,omitempty"`
+	PositionMS int `json:"position_ms,omitempty"`
+}
+
+// Validate checks the URIs and that the options describe a single thing to play.
+func (o PlaybackOptions) Validate() error {
+	if o.ContextURI != "" && len(o.URIs) > 0 {
+		return fmt.Errorf("context_uri and uris cannot be combined")
+	}
+	if o.ContextURI != "" {
+		if err := ValidateSpotifyURI(o.ContextURI, ContextURITypes...); err != nil {
+			return fmt.Errorf("context_uri: %w", err)
+		}
+	}
+	for _, uri := range o.URIs {
+		if err := ValidateSpotifyURI(uri, PlayableURITypes...); err != nil {
+			return fmt.Errorf("uris: %w", err)
+		}
+	}
+	if o.Offset != nil {
+		if o.ContextURI == "" && len(o.URIs) == 0 {
+			return fmt.Errorf("offset requires context_uri or uris")
+		}
+		if (o.Offset.Position != nil) == (o.Offset.URI != "") {
+			return fmt.Errorf("offset needs exactly one of position or uri")
+		}
+		if o.Offset.Position != nil && *o.Offset.Position < 0 {
+			return fmt.Errorf("offset.position must not be negative")
+		}
+		if o.Offset.URI != "" {
+			if err := ValidateSpotifyURI(o.Offset.URI, PlayableURITypes...); err != nil {
+				return fmt.Errorf("offset.uri: %w", err)
+			}
+		}
+	}
+	if o.PositionMS < 0 {
+		return fmt.Errorf("position_ms must not be negative")
+	}
+	return nil
+}
+
+// IsResume reports whether the options carry nothing to play, which makes StartPlayback a plain resume.
+func (o PlaybackOptions) IsResume() bool {
+	return o.ContextURI == "" && len(o.URIs) == 0 && o.Offset == nil && o.PositionMS == 0
+}
+
+// StartPlayback starts or resumes playback on deviceID, or on the active device when deviceID is empty.
+func StartPlayback(accessToken, deviceID string, opts PlaybackOptions) (int, error) {
+	trackURL := playerURL("/play", url.Values{}, deviceID)
 	statusCode := 500
-	payload := map[string]interface{}{
-		"context_uri": contextURI, 
-		"offset": map[string]interface{}{
-			"position": offsetPosition,
-		},
-		"position_ms": positionMS,
+	var reqBody io.Reader
+	if !opts.IsResume() {
+		body, err := json.Marshal(opts)
+		if err != nil {
+			return statusCode, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reqBody = bytes.NewBuffer(body)
 	}
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return statusCode, fmt.Errorf("failed to marshal request body: %w", err)
-	}
-	req, err := http.NewRequest("PUT", trackURL, bytes.NewBuffer(body))
+	req, err := http.NewRequest("PUT", trackURL, reqBody)
 	if err != nil {
 		return statusCode, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "application/json")
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -220,11 +296,8 @@ func StartPlayback(accessToken, deviceID, contextURI string, offsetPosition, pos
 }
 
 func PausePlayback(accessToken, deviceID string) (int, error) {
-	trackURL := fmt.Sprintf("%s/me/player/pause", BaseAPIURL)
+	trackURL := playerURL("/pause", url.Values{}, deviceID)
 	statusCode := 500
-	if deviceID != "" {
-		trackURL += "?device_id=" + deviceID
-	}
 	req, err := http.NewRequest("PUT", trackURL, nil)
 	if err != nil {
 		return statusCode, fmt.Errorf("failed to create request: %w", err)
@@ -249,11 +322,8 @@ func PausePlayback(accessToken, deviceID string) (int, error) {
 }
 
 func SkipNext(accessToken, deviceID string) (int, error) {
-	trackURL := fmt.Sprintf("%s/me/player/next", BaseAPIURL)
+	trackURL := playerURL("/next", url.Values{}, deviceID)
 	statusCode := 500
-	if deviceID != "" {
-		trackURL += "?device_id=" + deviceID
-	}
 	req, err := http.NewRequest("POST", trackURL, nil)
 	if err != nil {
 		return statusCode, fmt.Errorf("failed to create request: %w", err)
@@ -276,12 +346,8 @@ func SkipNext(accessToken, deviceID string) (int, error) {
 }
 
 func SkipPrev(accessToken, deviceID string) (int, error) {
-	trackURL := fmt.Sprintf("%s/me/player/previous", BaseAPIURL)
-	
+	trackURL := playerURL("/previous", url.Values{}, deviceID)
 	statusCode := 500
-	if deviceID != "" {
-		trackURL += "?device_id=" + deviceID
-	}
 	req, err := http.NewRequest("POST", trackURL, nil)
 	if err != nil {
 		return statusCode, fmt.Errorf("failed to create request: %w", err)
@@ -304,10 +370,7 @@ func SkipPrev(accessToken, deviceID string) (int, error) {
 
 }
 func SeekPosition(accessToken, deviceID string, positionMS int) (int, error) {
-	seekURL := fmt.Sprintf("%s/me/player/seek?position_ms=%d", BaseAPIURL, positionMS)
-	if deviceID != "" {
-		seekURL += "&device_id=" + deviceID
-	}
+	seekURL := playerURL("/seek", url.Values{"position_ms": {strconv.Itoa(positionMS)}}, deviceID)
 	req, err := http.NewRequest("PUT", seekURL, nil)
 	if err != nil {
 		return 500, fmt.Errorf("failed to create request: %w", err)
@@ -330,10 +393,7 @@ func SeekPosition(accessToken, deviceID string, positionMS int) (int, error) {
 }
 
 func ToggleRepeat(accessToken, deviceID string, state string) (int, error) {
-	repeatURL := fmt.Sprintf("%s/me/player/repeat?state=%s", BaseAPIURL, state)
-	if deviceID != "" {
-		repeatURL += "&device_id=" + deviceID
-	}
+	repeatURL := playerURL("/repeat", url.Values{"state": {state}}, deviceID)
 	req, err := http.NewRequest("PUT", repeatURL, nil)
 	if err != nil {
 		return 500, fmt.Errorf("failed to create request: %w", err)
@@ -355,11 +415,33 @@ func ToggleRepeat(accessToken, deviceID string, state string) (int, error) {
 	return resp.StatusCode, nil
 }
 
-func SetPlaybackVolume(accessToken, deviceID string, volumePercent int) (int, error) {
-	volumeURL := fmt.Sprintf("%s/me/player/volume?volume_percent=%d", BaseAPIURL, volumePercent)
-	if deviceID != "" {
-		volumeURL += "&device_id=" + deviceID
+// NextRepeatState returns the repeat state after state in the cycle off, context, track.
+func NextRepeatState(state string) string {
+	switch state {
+	case "off":
+		return "context"
+	case "context":
+		return "track"
 	}
+	return "off"
+}
+
+// CycleRepeat reads the current repeat state and moves it on to the next one, returning the new state.
+func CycleRepeat(accessToken, deviceID string) (string, int, error) {
+	playback, status, err := currentPlayback(accessToken)
+	if err != nil {
+		return "", status, err
+	}
+	state := NextRepeatState(playback.RepeatState)
+	status, err = ToggleRepeat(accessToken, deviceID, state)
+	if err != nil {
+		return "", status, err
+	}
+	return state, status, nil
+}
+
+func SetPlaybackVolume(accessToken, deviceID string, volumePercent int) (int, error) {
+	volumeURL := playerURL("/volume", url.Values{"volume_percent": {strconv.Itoa(volumePercent)}}, deviceID)
 	req, err := http.NewRequest("PUT", volumeURL, nil)
 	if err != nil {
 		return 500, fmt.Errorf("failed to create request: %w", err)
@@ -382,10 +464,7 @@ func SetPlaybackVolume(accessToken, deviceID string, volumePercent int) (int, er
 }
 
 func ToggleShuffle(accessToken, deviceID string, state bool) (int, error) {
-	shuffleURL := fmt.Sprintf("%s/me/player/shuffle?state=%t", BaseAPIURL, state)
-	if deviceID != "" {
-		shuffleURL += "&device_id=" + deviceID
-	}
+	shuffleURL := playerURL("/shuffle", url.Values{"state": {strconv.FormatBool(state)}}, deviceID)
 	req, err := http.NewRequest("PUT", shuffleURL, nil)
 	if err != nil {
 		return 500, fmt.Errorf("failed to create request: %w", err)
@@ -407,16 +486,46 @@ func ToggleShuffle(accessToken, deviceID string, state bool) (int, error) {
 	return resp.StatusCode, nil
 }
 
-type RecentlyPlayedResponse struct {
-	Items []RecentlyPlayedItem `json:"items"`
+// FlipShuffle reads the current shuffle state and turns it the other way, returning the new state.
+func FlipShuffle(accessToken, deviceID string) (bool, int, error) {
+	playback, status, err := currentPlayback(accessToken)
+	if err != nil {
+		return false, status, err
+	}
+	state := !playback.ShuffleState
+	status, err = ToggleShuffle(accessToken, deviceID, state)
+	if err != nil {
+		return false, status, err
+	}
+	return state, status, nil
 }
 
+// currentPlayback is GetPlayBack with no active playback reported as 404, since there is no state to read.
+func currentPlayback(accessToken string) (*PlayBackResponse, int, error) {
+	playback, status, err := GetPlayBack(accessToken)
+	if status == http.StatusNoContent {
+		return nil, http.StatusNotFound, fmt.Errorf("nothing is playing")
+	}
+	if err != nil {
+		return nil, status, err
+	}
+	return playback, status, nil
+}
+
+// RecentlyPlayedResponse is a cursor-based page of recently played items.
+type RecentlyPlayedResponse = CursorPaging[RecentlyPlayedItem]
+
 type RecentlyPlayedItem struct {
 	Track      *Track      `json:"track"`
 	PlayedAt   string      `json:"played_at"`
 	Context    *Context    `json:"context"`
 }
 
+// PlayedAtTime parses PlayedAt, which Spotify reports as an RFC3339 timestamp.
+func (item RecentlyPlayedItem) PlayedAtTime() (time.Time, error) {
+	return time.Parse(time.RFC3339, item.PlayedAt)
+}
+
 type Context struct {
 	ExternalUrls map[string]string `json:"external_urls"`
 	Href         string            `json:"href"`
@@ -424,32 +533,83 @@ type Context struct {
 	URI          string            `json:"uri"`
 }
 
-func GetRecentlyPlayed(accessToken string) (*RecentlyPlayedResponse, int, error) {
-	recentlyPlayedURL := fmt.Sprintf("%s/me/player/recently-played", BaseAPIURL)
-	req, err := http.NewRequest("GET", recentlyPlayedURL, nil)
-	if err != nil {
-		return nil, 500, fmt.Errorf("failed to create request: %w", err)
-	}
+// RecentlyPlayedOptions holds the optional query parameters of the recently played endpoint.
+// After and Before are Unix timestamps in milliseconds; Spotify accepts at most one of them.
+type RecentlyPlayedOptions struct {
+	Limit  int
+	After  int64
+	Before int64
+}
 
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, 500, fmt.Errorf("failed to make request: %w", err)
+func (o RecentlyPlayedOptions) values() url.Values {
+	params := url.Values{}
+	if o.Limit > 0 {
+		params.Set("limit", strconv.Itoa(o.Limit))
 	}
-	defer resp.Body.Close()
+	if o.After > 0 {
+		params.Set("after", strconv.FormatInt(o.After, 10))
+	}
+	if o.Before > 0 {
+		params.Set("before", strconv.FormatInt(o.Before, 10))
+	}
+	return params
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, resp.StatusCode, fmt.Errorf("failed to get recently played tracks: %s", body)
+func recentlyPlayedURL(opts RecentlyPlayedOptions) string {
+	recentlyPlayedURL := fmt.Sprintf("%s/me/player/recently-played", BaseAPIURL)
+	if params := opts.values(); len(params) > 0 {
+		recentlyPlayedURL += "?" + params.Encode()
 	}
+	return recentlyPlayedURL
+}
 
+func GetRecentlyPlayed(accessToken string, opts RecentlyPlayedOptions) (*RecentlyPlayedResponse, int, error) {
+	if opts.After > 0 && opts.Before > 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("only one of after or before may be set")
+	}
 	var results RecentlyPlayedResponse
-	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
-		return nil, resp.StatusCode, fmt.Errorf("failed to parse response: %w", err)
+	status, err := getJSON(accessToken, recentlyPlayedURL(opts), "recently played tracks", &results)
+	if err != nil {
+		return nil, status, err
 	}
+	return &results, status, nil
+}
 
-	return &results, resp.StatusCode, nil
+// AllRecentlyPlayed lazily walks the recently played history by following next links.
+// Spotify's next links page backwards in time, so items are yielded newest first.
+func AllRecentlyPlayed(accessToken string, opts RecentlyPlayedOptions) iter.Seq2[RecentlyPlayedItem, error] {
+	return walkItems(recentlyPlayedURL(opts),
+		func(pageURL string) (*RecentlyPlayedResponse, int, error) {
+			var results RecentlyPlayedResponse
+			status, err := getJSON(accessToken, pageURL, "recently played tracks", &results)
+			return &results, status, err
+		})
+}
+
+// RecentlyPlayedBetween collects every item played after the after time and before the before time,
+// walking the cursors backwards until the window is covered. A zero before means now and a zero after
+// means as far back as Spotify keeps history. Items are returned oldest first.
+func RecentlyPlayedBetween(accessToken string, after, before time.Time) ([]RecentlyPlayedItem, int, error) {
+	opts := RecentlyPlayedOptions{Limit: 50}
+	if !before.IsZero() {
+		opts.Before = before.UnixMilli()
+	}
+	var items []RecentlyPlayedItem
+	for item, err := range AllRecentlyPlayed(accessToken, opts) {
+		if err != nil {
+			return nil, ErrorStatus(err), err
+		}
+		playedAt, err := item.PlayedAtTime()
+		if err != nil {
+			return nil, 500, fmt.Errorf("failed to parse played_at: %w", err)
+		}
+		if !after.IsZero() && !playedAt.After(after) {
+			break
+		}
+		items = append(items, item)
+	}
+	slices.Reverse(items)
+	return items, http.StatusOK, nil
 }
 
 type QueueResponse struct {
@@ -486,10 +646,7 @@ func GetUsersQueue(accessToken string) (*QueueResponse, int, error) {
 }
 
 func AddToQueue(accessToken, deviceID, uri string) (int, error) {
-	queueURL := fmt.Sprintf("%s/me/player/queue?uri=%s", BaseAPIURL, uri)
-	if deviceID != "" {
-		queueURL += "&device_id=" + deviceID
-	}
+	queueURL := playerURL("/queue", url.Values{"uri": {uri}}, deviceID)
 	req, err := http.NewRequest("POST", queueURL, nil)
 	if err != nil {
 		return 500, fmt.Errorf("failed to create request: %w", err)
@@ -503,10 +660,10 @@ func AddToQueue(accessToken, deviceID, uri string) (int, error) {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusCreated {
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
 		return resp.StatusCode, fmt.Errorf("failed to add to playback queue: %s", body)
 	}
 
-	return http.StatusCreated, nil
+	return resp.StatusCode, nil
 }
@@ -0,0 +1,53 @@
+package api
+
+import "testing"
+
+func TestPlaybackOptionsValidate(t *testing.T) {
+	zero, negative := 0, -1
+	tests := []struct {
+		name    string
+		opts    PlaybackOptions
+		wantErr bool
+	}{
+		{"resume", PlaybackOptions{}, false},
+		{"context", PlaybackOptions{ContextURI: testAlbumURI}, false},
+		{"uris", PlaybackOptions{URIs: []string{testTrackURI, testEpisodeURI}}, false},
+		{"context with position offset", PlaybackOptions{ContextURI: testPlaylistURI, Offset: &PlaybackOffset{Position: &zero}}, false},
+		{"context with uri offset", PlaybackOptions{ContextURI: testAlbumURI, Offset: &PlaybackOffset{URI: testTrackURI}}, false},
+		{"start position", PlaybackOptions{URIs: []string{testTrackURI}, PositionMS: 30000}, false},
+		{"context and uris", PlaybackOptions{ContextURI: testAlbumURI, URIs: []string{testTrackURI}}, true},
+		{"track as context", PlaybackOptions{ContextURI: testTrackURI}, true},
+		{"album in uris", PlaybackOptions{URIs: []string{testAlbumURI}}, true},
+		{"offset without context", PlaybackOptions{Offset: &PlaybackOffset{Position: &zero}}, true},
+		{"offset with both", PlaybackOptions{ContextURI: testAlbumURI, Offset: &PlaybackOffset{Position: &zero, URI: testTrackURI}}, true},
+		{"offset with neither", PlaybackOptions{ContextURI: testAlbumURI, Offset: &PlaybackOffset{}}, true},
+		{"negative offset", PlaybackOptions{ContextURI: testAlbumURI, Offset: &PlaybackOffset{Position: &negative}}, true},
+		{"album as offset uri", PlaybackOptions{ContextURI: testPlaylistURI, Offset: &PlaybackOffset{URI: testAlbumURI}}, true},
+		{"negative position", PlaybackOptions{URIs: []string{testTrackURI}, PositionMS: -1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNextRepeatState(t *testing.T) {
+	tests := []struct {
+		state string
+		want  string
+	}{
+		{"off", "context"},
+		{"context", "track"},
+		{"track", "off"},
+		{"", "off"},
+		{"unknown", "off"},
+	}
+	for _, tt := range tests {
+		if got := NextRepeatState(tt.state); got != tt.want {
+			t.Errorf("NextRepeatState(%q) = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}
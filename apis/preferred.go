@@ -0,0 +1,110 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// preferredSettle gives Spotify a moment to make a freshly transferred device active before retrying.
+const preferredSettle = 500 * time.Millisecond
+
+// PreferredDevices keeps each user's ordered list of devices to wake up when a command fails
+// because no device is active. Entries are device names, aliases or IDs, matched like DeviceResolver.Find.
+type PreferredDevices struct {
+	devices   *DeviceResolver
+	path      string
+	preferred map[string][]string
+	mutx      sync.RWMutex
+}
+
+func NewPreferredDevices(devices *DeviceResolver, path string) (*PreferredDevices, error) {
+	pd := &PreferredDevices{devices: devices, path: path, preferred: map[string][]string{}}
+	if err := loadJSON(path, &pd.preferred); err != nil {
+		return nil, err
+	}
+	return pd, nil
+}
+
+// List returns the user's preferred devices, most preferred first.
+func (pd *PreferredDevices) List(userID string) []string {
+	pd.mutx.RLock()
+	defer pd.mutx.RUnlock()
+
+	return append([]string{}, pd.preferred[userID]...)
+}
+
+// Set replaces the user's preferred devices. An empty list turns the fallback off.
+func (pd *PreferredDevices) Set(userID string, devices []string) (int, error) {
+	cleaned := make([]string, 0, len(devices))
+	for _, device := range devices {
+		if device = strings.TrimSpace(device); device == "" {
+			return http.StatusBadRequest, fmt.Errorf("device names must not be empty")
+		}
+		cleaned = append(cleaned, device)
+	}
+
+	pd.mutx.Lock()
+	defer pd.mutx.Unlock()
+
+	previous, existed := pd.preferred[userID]
+	if len(cleaned) == 0 {
+		delete(pd.preferred, userID)
+	} else {
+		pd.preferred[userID] = cleaned
+	}
+	if err := saveJSON(pd.path, pd.preferred); err != nil {
+		if existed {
+			pd.preferred[userID] = previous
+		} else {
+			delete(pd.preferred, userID)
+		}
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// Activate transfers playback, without starting it, to the first preferred device that is available.
+func (pd *PreferredDevices) Activate(accessToken, userID string) (*DeviceData, int, error) {
+	preferred := pd.List(userID)
+	if len(preferred) == 0 {
+		return nil, http.StatusNotFound, fmt.Errorf("no preferred devices configured")
+	}
+	devices, status, err := GetDevices(accessToken)
+	if err != nil {
+		return nil, status, err
+	}
+	for _, entry := range preferred {
+		matches := matchDevices(devices.Devices, pd.devices.expandAlias(userID, entry))
+		if len(matches) != 1 || matches[0].IsRestricted {
+			continue
+		}
+		device := matches[0]
+		if status, err := TransferPlayback(accessToken, device.ID, false); err != nil {
+			return nil, status, err
+		}
+		return &device, http.StatusOK, nil
+	}
+	return nil, http.StatusNotFound, fmt.Errorf("none of the preferred devices are available; available devices: %s", describeDevices(devices.Devices))
+}
+
+// WithFallback runs command and, when it fails because no device is active, activates the first
+// available preferred device and runs command once more.
+func (pd *PreferredDevices) WithFallback(accessToken, userID string, command func() (int, error)) (int, error) {
+	status, err := command()
+	if !IsNoActiveDevice(status, err) {
+		return status, err
+	}
+	if _, _, activateErr := pd.Activate(accessToken, userID); activateErr != nil {
+		return status, fmt.Errorf("%w (preferred device fallback: %v)", err, activateErr)
+	}
+	time.Sleep(preferredSettle)
+	return command()
+}
+
+// IsNoActiveDevice reports whether a player command failed with Spotify's NO_ACTIVE_DEVICE reason.
+func IsNoActiveDevice(status int, err error) bool {
+	return err != nil && status == http.StatusNotFound && strings.Contains(err.Error(), "NO_ACTIVE_DEVICE")
+}
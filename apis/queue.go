@@ -0,0 +1,142 @@
+package api
+
+import (
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+)
+
+// maxQueueBatch caps how many items one batch may queue after albums and playlists are expanded.
+const maxQueueBatch = 200
+
+type playlistItem struct {
+	Track *Track `json:"track"`
+}
+
+// AllAlbumTracks lazily walks every page of an album's tracks.
+func AllAlbumTracks(accessToken, albumID string) iter.Seq2[Track, error] {
+	firstURL := BaseAPIURL + "/albums/" + url.PathEscape(albumID) + "/tracks?limit=50"
+	return walkItems(firstURL, func(pageURL string) (*Paging[Track], int, error) {
+		var results Paging[Track]
+		status, err := getJSON(accessToken, pageURL, "album tracks", &results)
+		return &results, status, err
+	})
+}
+
+// AllPlaylistTracks lazily walks every page of a playlist's tracks and episodes,
+// leaving out entries Spotify no longer returns a track for.
+func AllPlaylistTracks(accessToken, playlistID string) iter.Seq2[Track, error] {
+	firstURL := BaseAPIURL + "/playlists/" + url.PathEscape(playlistID) + "/tracks?limit=100"
+	items := walkItems(firstURL, func(pageURL string) (*Paging[playlistItem], int, error) {
+		var results Paging[playlistItem]
+		status, err := getJSON(accessToken, pageURL, "playlist tracks", &results)
+		return &results, status, err
+	})
+	return func(yield func(Track, error) bool) {
+		for item, err := range items {
+			if err != nil {
+				yield(Track{}, err)
+				return
+			}
+			if item.Track == nil || item.Track.URI == "" {
+				continue
+			}
+			if !yield(*item.Track, nil) {
+				return
+			}
+		}
+	}
+}
+
+// QueueItem is one track or episode to queue. Source is the album or playlist it was expanded from.
+type QueueItem struct {
+	URI    string `json:"uri"`
+	Source string `json:"source,omitempty"`
+}
+
+// QueueItemResult reports what happened to one item of a batch. Skipped gives the reason an item was left out.
+type QueueItemResult struct {
+	QueueItem
+	Queued  bool   `json:"queued"`
+	Skipped string `json:"skipped,omitempty"`
+	Status  int    `json:"status,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ExpandQueueURIs validates uris and replaces album and playlist URIs with their tracks, keeping the order.
+func ExpandQueueURIs(accessToken string, uris []string) ([]QueueItem, int, error) {
+	var items []QueueItem
+	for _, uri := range uris {
+		kind, id, err := ParseSpotifyURI(uri)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		var tracks iter.Seq2[Track, error]
+		switch kind {
+		case "track", "episode":
+			items = append(items, QueueItem{URI: uri})
+		case "album":
+			tracks = AllAlbumTracks(accessToken, id)
+		case "playlist":
+			tracks = AllPlaylistTracks(accessToken, id)
+		default:
+			return nil, http.StatusBadRequest, fmt.Errorf("%q is a %s URI, expected track, episode, album or playlist", uri, kind)
+		}
+		if tracks != nil {
+			for track, err := range tracks {
+				if err != nil {
+					return nil, ErrorStatus(err), err
+				}
+				items = append(items, QueueItem{URI: track.URI, Source: uri})
+				if len(items) > maxQueueBatch {
+					break
+				}
+			}
+		}
+		if len(items) > maxQueueBatch {
+			return nil, http.StatusBadRequest, fmt.Errorf("a batch can queue at most %d items", maxQueueBatch)
+		}
+	}
+	return items, http.StatusOK, nil
+}
+
+// QueueBatch queues items one after another with add, so they keep their order. With skipQueued set,
+// items already in the user's queue, or earlier in the batch, are skipped. Failed items are reported
+// and the rest are still queued; the returned bool is false if any item failed.
+func QueueBatch(accessToken string, items []QueueItem, skipQueued bool, add func(uri string) (int, error)) ([]QueueItemResult, int, bool, error) {
+	queued := map[string]bool{}
+	if skipQueued {
+		queue, status, err := GetUsersQueue(accessToken)
+		if err != nil {
+			return nil, status, false, err
+		}
+		for _, track := range queue.Queue {
+			if track != nil {
+				queued[track.URI] = true
+			}
+		}
+	}
+
+	results := make([]QueueItemResult, 0, len(items))
+	ok := true
+	for _, item := range items {
+		result := QueueItemResult{QueueItem: item}
+		if skipQueued && queued[item.URI] {
+			result.Skipped = "already queued"
+			results = append(results, result)
+			continue
+		}
+		status, err := add(item.URI)
+		result.Status = status
+		if err != nil {
+			result.Error = err.Error()
+			ok = false
+		} else {
+			result.Queued = true
+			queued[item.URI] = true
+		}
+		results = append(results, result)
+	}
+	return results, http.StatusOK, ok, nil
+}
@@ -12,7 +12,9 @@ type Track struct {
 	Name string `json:"name"`
 	DurationMS int32 `json:"duration_ms"`
 	ID string `json:"id"`
+	URI string `json:"uri"`
 	DataType string `json:"type"`
+	Explicit bool `json:"explicit"`
 	Album struct {
 		Name string `json:"name"`
 		ID string `json:"id"`
@@ -24,17 +26,18 @@ type Track struct {
 }
 
 type SearchResponse struct {
-	Tracks struct {
-		Items []Track `json:"items"`
-	} `json:"tracks"`
+	Tracks Paging[Track] `json:"tracks"`
 }
 
-func SearchSpotify(accessToken, query, searchType string, limit int32) (*SearchResponse, error) {
+func SearchSpotify(accessToken, query, searchType string, limit, offset int32) (*SearchResponse, error) {
 	searchURL := BaseAPIURL + "/search"
 	params := url.Values{}
 	params.Set("q", query)
 	params.Set("type", searchType)
 	params.Set("limit", fmt.Sprintf("%d", limit))
+	if offset > 0 {
+		params.Set("offset", fmt.Sprintf("%d", offset))
+	}
 
 	req, err := http.NewRequest("GET", searchURL+"?"+params.Encode(), nil)
 	if err != nil {
@@ -60,4 +63,13 @@ func SearchSpotify(accessToken, query, searchType string, limit int32) (*SearchR
 	}
 
 	return &results, nil
-}
\ No newline at end of file
+}
+// GetTrack fetches a single track by its Spotify ID.
+func GetTrack(accessToken, trackID string) (*Track, int, error) {
+	var track Track
+	status, err := getJSON(accessToken, BaseAPIURL+"/tracks/"+url.PathEscape(trackID), "track", &track)
+	if err != nil {
+		return nil, status, err
+	}
+	return &track, status, nil
+}
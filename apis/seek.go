@@ -0,0 +1,73 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ParseSeekOffset reads a relative seek such as "+15s" or "-30s". The sign is required so a
+// relative seek is never mistaken for an absolute position.
+func ParseSeekOffset(offset string) (time.Duration, error) {
+	if !strings.HasPrefix(offset, "+") && !strings.HasPrefix(offset, "-") {
+		return 0, fmt.Errorf("offset %q must start with + or -", offset)
+	}
+	d, err := time.ParseDuration(offset)
+	if err != nil {
+		return 0, fmt.Errorf("offset %q is not a duration like +15s or -30s", offset)
+	}
+	return d, nil
+}
+
+// SeekRelative moves the playback position by offset, clamped to the current track, and returns the new position.
+func SeekRelative(accessToken, deviceID string, offset time.Duration) (int, int, error) {
+	return seekWithin(accessToken, deviceID, relativePosition(offset))
+}
+
+func relativePosition(offset time.Duration) func(progressMS, durationMS int64) int64 {
+	return func(progressMS, durationMS int64) int64 {
+		return progressMS + offset.Milliseconds()
+	}
+}
+
+// SeekPercent moves to percent (0 to 100) of the current track and returns the new position.
+func SeekPercent(accessToken, deviceID string, percent float64) (int, int, error) {
+	if percent < 0 || percent > 100 {
+		return 0, http.StatusBadRequest, fmt.Errorf("percent must be between 0 and 100")
+	}
+	return seekWithin(accessToken, deviceID, percentPosition(percent))
+}
+
+func percentPosition(percent float64) func(progressMS, durationMS int64) int64 {
+	return func(progressMS, durationMS int64) int64 {
+		return int64(float64(durationMS) * percent / 100)
+	}
+}
+
+// seekWithin seeks to the position computed from the current progress and track length. The position
+// is kept inside the track, since seeking past its end would skip to the next one.
+func seekWithin(accessToken, deviceID string, position func(progressMS, durationMS int64) int64) (int, int, error) {
+	state, status, err := GetPlayBack(accessToken)
+	if err != nil {
+		return 0, status, err
+	}
+	target, err := seekTarget(state, position)
+	if err != nil {
+		return 0, http.StatusConflict, err
+	}
+	status, err = SeekPosition(accessToken, deviceID, int(target))
+	if err != nil {
+		return 0, status, err
+	}
+	return int(target), status, nil
+}
+
+// seekTarget computes the position for the playback state, clamped to 0 through the last millisecond of the track.
+func seekTarget(state *PlayBackResponse, position func(progressMS, durationMS int64) int64) (int64, error) {
+	if state.Item == nil || state.Item.DurationMS <= 0 {
+		return 0, fmt.Errorf("nothing is playing")
+	}
+	durationMS := int64(state.Item.DurationMS)
+	return min(max(position(int64(state.ProgressMS), durationMS), 0), durationMS-1), nil
+}
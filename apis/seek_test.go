@@ -0,0 +1,61 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSeekOffset(t *testing.T) {
+	tests := []struct {
+		offset  string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"+15s", 15 * time.Second, false},
+		{"-30s", -30 * time.Second, false},
+		{"+1m30s", 90 * time.Second, false},
+		{"-500ms", -500 * time.Millisecond, false},
+		{"15s", 0, true},
+		{"", 0, true},
+		{"+", 0, true},
+		{"+15", 0, true},
+		{"+fast", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSeekOffset(tt.offset)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSeekOffset(%q) = %v, %v, want %v, error %v", tt.offset, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSeekTarget(t *testing.T) {
+	playing := func(progressMS uint64) *PlayBackResponse {
+		return &PlayBackResponse{ProgressMS: progressMS, Item: &Track{DurationMS: 200000}}
+	}
+	tests := []struct {
+		name     string
+		state    *PlayBackResponse
+		position func(progressMS, durationMS int64) int64
+		want     int64
+		wantErr  bool
+	}{
+		{"forward", playing(60000), relativePosition(15 * time.Second), 75000, false},
+		{"back", playing(60000), relativePosition(-30 * time.Second), 30000, false},
+		{"back past the start", playing(10000), relativePosition(-30 * time.Second), 0, false},
+		{"forward past the end", playing(190000), relativePosition(30 * time.Second), 199999, false},
+		{"half way", playing(0), percentPosition(50), 100000, false},
+		{"start", playing(60000), percentPosition(0), 0, false},
+		{"end stays inside the track", playing(0), percentPosition(100), 199999, false},
+		{"nothing playing", &PlayBackResponse{}, percentPosition(50), 0, true},
+		{"zero length item", &PlayBackResponse{Item: &Track{}}, percentPosition(50), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := seekTarget(tt.state, tt.position)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("seekTarget() = %d, %v, want %d, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	skipRuleTick = 2 * time.Second
+	// skipAuditLimit caps how many skips are kept in each user's audit log.
+	skipAuditLimit = 500
+)
+
+// SkipRules decide which tracks are skipped as soon as they start playing.
+// MinSeconds and MaxSeconds of 0 leave the track length unchecked.
+type SkipRules struct {
+	Enabled          bool     `json:"enabled"`
+	BlockExplicit    bool     `json:"block_explicit"`
+	BlockedArtistIDs []string `json:"blocked_artist_ids"`
+	BlockedTrackIDs  []string `json:"blocked_track_ids"`
+	MinSeconds       int      `json:"min_seconds"`
+	MaxSeconds       int      `json:"max_seconds"`
+}
+
+func (r *SkipRules) Validate() error {
+	if r.MinSeconds < 0 || r.MaxSeconds < 0 {
+		return fmt.Errorf("min_seconds and max_seconds must not be negative")
+	}
+	if r.MaxSeconds > 0 && r.MinSeconds > r.MaxSeconds {
+		return fmt.Errorf("min_seconds must not be greater than max_seconds")
+	}
+	for _, ids := range [][]string{r.BlockedArtistIDs, r.BlockedTrackIDs} {
+		for _, id := range ids {
+			if !spotifyIDPattern.MatchString(id) {
+				return fmt.Errorf("%q is not a Spotify ID", id)
+			}
+		}
+	}
+	return nil
+}
+
+// Match returns the rule a track breaks and a readable reason, or an empty rule when it may play.
+func (r *SkipRules) Match(track *Track) (string, string) {
+	if slices.Contains(r.BlockedTrackIDs, track.ID) {
+		return "blocked_track", "track is blocked"
+	}
+	for _, artist := range track.Artists {
+		if slices.Contains(r.BlockedArtistIDs, artist.ID) {
+			return "blocked_artist", fmt.Sprintf("artist %s is blocked", artist.Name)
+		}
+	}
+	if r.BlockExplicit && track.Explicit {
+		return "explicit", "track is explicit"
+	}
+	seconds := int(track.DurationMS / 1000)
+	if r.MinSeconds > 0 && seconds < r.MinSeconds {
+		return "too_short", fmt.Sprintf("track is %ds, shorter than %ds", seconds, r.MinSeconds)
+	}
+	if r.MaxSeconds > 0 && seconds > r.MaxSeconds {
+		return "too_long", fmt.Sprintf("track is %ds, longer than %ds", seconds, r.MaxSeconds)
+	}
+	return "", ""
+}
+
+// SkipAuditEntry records one track the skip rules matched.
+type SkipAuditEntry struct {
+	Time      time.Time `json:"time"`
+	TrackID   string    `json:"track_id"`
+	TrackName string    `json:"track_name"`
+	Artists   string    `json:"artists"`
+	Rule      string    `json:"rule"`
+	Reason    string    `json:"reason"`
+	Status    int       `json:"status"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type skipRuleState struct {
+	Rules map[string]*SkipRules       `json:"rules"`
+	Audit map[string][]SkipAuditEntry `json:"audit"`
+}
+
+// SkipRuleWatcher applies the logged-in user's skip rules to whatever starts playing and keeps
+// an audit log of the skips, persisted with the rules in a JSON file.
+type SkipRuleWatcher struct {
+	tokenMx *TokenManager
+	path    string
+	state   skipRuleState
+	// lastTrackID is the last track the rules were checked against, so each track is judged once.
+	// It is cleared again when skipping the track fails.
+	lastTrackID string
+	// failedTrackID is the track whose skip last failed. Retries of it are not audited again.
+	failedTrackID string
+	mutx          sync.Mutex
+}
+
+func NewSkipRuleWatcher(tokenMx *TokenManager, path string) (*SkipRuleWatcher, error) {
+	sw := &SkipRuleWatcher{
+		tokenMx: tokenMx,
+		path:    path,
+		state:   skipRuleState{Rules: map[string]*SkipRules{}, Audit: map[string][]SkipAuditEntry{}},
+	}
+	if err := loadJSON(path, &sw.state); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+// Start checks the current track in the background until ctx is cancelled.
+func (sw *SkipRuleWatcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(skipRuleTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sw.check()
+			}
+		}
+	}()
+}
+
+// Rules returns the user's skip rules.
+func (sw *SkipRuleWatcher) Rules(userID string) SkipRules {
+	sw.mutx.Lock()
+	defer sw.mutx.Unlock()
+
+	if rules := sw.state.Rules[userID]; rules != nil {
+		return *rules
+	}
+	return SkipRules{BlockedArtistIDs: []string{}, BlockedTrackIDs: []string{}}
+}
+
+// SetRules replaces the user's skip rules. The current track is checked again against the new rules.
+func (sw *SkipRuleWatcher) SetRules(userID string, rules SkipRules) (int, error) {
+	if err := rules.Validate(); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if rules.BlockedArtistIDs == nil {
+		rules.BlockedArtistIDs = []string{}
+	}
+	if rules.BlockedTrackIDs == nil {
+		rules.BlockedTrackIDs = []string{}
+	}
+	sw.mutx.Lock()
+	defer sw.mutx.Unlock()
+
+	previous := sw.state.Rules[userID]
+	sw.state.Rules[userID] = &rules
+	if err := saveJSON(sw.path, sw.state); err != nil {
+		if previous != nil {
+			sw.state.Rules[userID] = previous
+		} else {
+			delete(sw.state.Rules, userID)
+		}
+		return http.StatusInternalServerError, err
+	}
+	sw.lastTrackID = ""
+	return http.StatusOK, nil
+}
+
+// Audit returns the user's most recent skips, newest first.
+func (sw *SkipRuleWatcher) Audit(userID string, limit int) []SkipAuditEntry {
+	sw.mutx.Lock()
+	defer sw.mutx.Unlock()
+
+	audit := sw.state.Audit[userID]
+	entries := make([]SkipAuditEntry, 0, len(audit))
+	for i := len(audit) - 1; i >= 0 && (limit <= 0 || len(entries) < limit); i-- {
+		entries = append(entries, audit[i])
+	}
+	return entries
+}
+
+// check skips the current track when it breaks the logged-in user's rules and records the skip.
+func (sw *SkipRuleWatcher) check() {
+	accessToken, valid := sw.tokenMx.GetToken()
+	userID := sw.tokenMx.GetUser()
+	if !valid || userID == "" {
+		return
+	}
+	rules := sw.Rules(userID)
+	if !rules.Enabled {
+		return
+	}
+	current, _, err := GetCurrentPlayingTrack(accessToken)
+	if err != nil || current.Item == nil || !current.IsPlaying {
+		return
+	}
+
+	sw.mutx.Lock()
+	seen := sw.lastTrackID == current.Item.ID
+	sw.lastTrackID = current.Item.ID
+	sw.mutx.Unlock()
+	if seen {
+		return
+	}
+	rule, reason := rules.Match(current.Item)
+	if rule == "" {
+		return
+	}
+
+	status, err := SkipNext(accessToken, "")
+	entry := SkipAuditEntry{
+		Time:      time.Now().UTC(),
+		TrackID:   current.Item.ID,
+		TrackName: current.Item.Name,
+		Rule:      rule,
+		Reason:    reason,
+		Status:    status,
+	}
+	var artists []string
+	for _, artist := range current.Item.Artists {
+		artists = append(artists, artist.Name)
+	}
+	entry.Artists = strings.Join(artists, ", ")
+
+	sw.mutx.Lock()
+	defer sw.mutx.Unlock()
+
+	if err != nil {
+		// Forget a track that could not be skipped so the next check tries again,
+		// but only audit the first failure so the retries do not fill the log.
+		if sw.lastTrackID == current.Item.ID {
+			sw.lastTrackID = ""
+		}
+		if sw.failedTrackID == current.Item.ID {
+			return
+		}
+		sw.failedTrackID = current.Item.ID
+		entry.Error = err.Error()
+		log.Printf("skip rules: %v", err)
+	} else {
+		sw.failedTrackID = ""
+	}
+	audit := append(sw.state.Audit[userID], entry)
+	if len(audit) > skipAuditLimit {
+		audit = audit[len(audit)-skipAuditLimit:]
+	}
+	sw.state.Audit[userID] = audit
+	if err := saveJSON(sw.path, sw.state); err != nil {
+		log.Printf("skip rules: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import "testing"
+
+func TestSkipRulesValidate(t *testing.T) {
+	const id = "4uLU6hMCjMI75M1A2tKUQC"
+	tests := []struct {
+		name    string
+		rules   SkipRules
+		wantErr bool
+	}{
+		{"empty", SkipRules{}, false},
+		{"full", SkipRules{Enabled: true, BlockExplicit: true, BlockedArtistIDs: []string{id}, BlockedTrackIDs: []string{id}, MinSeconds: 60, MaxSeconds: 600}, false},
+		{"only a minimum", SkipRules{MinSeconds: 60}, false},
+		{"equal bounds", SkipRules{MinSeconds: 60, MaxSeconds: 60}, false},
+		{"negative minimum", SkipRules{MinSeconds: -1}, true},
+		{"negative maximum", SkipRules{MaxSeconds: -1}, true},
+		{"minimum above maximum", SkipRules{MinSeconds: 600, MaxSeconds: 60}, true},
+		{"bad artist ID", SkipRules{BlockedArtistIDs: []string{"spotify:artist:" + id}}, true},
+		{"bad track ID", SkipRules{BlockedTrackIDs: []string{id, "short"}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.rules.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSkipRulesMatch(t *testing.T) {
+	rules := SkipRules{
+		BlockExplicit:    true,
+		BlockedArtistIDs: []string{"blocked-artist"},
+		BlockedTrackIDs:  []string{"blocked-track"},
+		MinSeconds:       60,
+		MaxSeconds:       600,
+	}
+	explicit := statsTrack(t, "explicit", "artist", 180000)
+	explicit.Explicit = true
+	explicitByBlocked := statsTrack(t, "explicit-blocked", "blocked-artist", 180000)
+	explicitByBlocked.Explicit = true
+
+	tests := []struct {
+		name     string
+		rules    SkipRules
+		track    *Track
+		wantRule string
+	}{
+		{"allowed", rules, statsTrack(t, "ok", "artist", 180000), ""},
+		{"blocked track", rules, statsTrack(t, "blocked-track", "artist", 180000), "blocked_track"},
+		{"blocked artist", rules, statsTrack(t, "other", "blocked-artist", 180000), "blocked_artist"},
+		{"explicit", rules, explicit, "explicit"},
+		{"blocked artist wins over explicit", rules, explicitByBlocked, "blocked_artist"},
+		{"explicit allowed", SkipRules{}, explicit, ""},
+		{"too short", rules, statsTrack(t, "short", "artist", 59999), "too_short"},
+		{"exactly the minimum", rules, statsTrack(t, "min", "artist", 60000), ""},
+		{"exactly the maximum", rules, statsTrack(t, "max", "artist", 600999), ""},
+		{"too long", rules, statsTrack(t, "long", "artist", 601000), "too_long"},
+		{"no length bounds", SkipRules{}, statsTrack(t, "long", "artist", 3600000), ""},
+	}
+	for _, tt := range tests {
+		rule, reason := tt.rules.Match(tt.track)
+		if rule != tt.wantRule || (reason == "") != (tt.wantRule == "") {
+			t.Errorf("%s: Match() = %q, %q, want rule %q", tt.name, rule, reason, tt.wantRule)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	sleepTick = 2 * time.Second
+	// sleepTrackLead pauses slightly before the last track ends so Spotify has no time to start the next one.
+	sleepTrackLead = time.Second
+)
+
+// SleepTimer pauses playback either at a fixed time or after a number of tracks, optionally fading out first.
+// Track based timers count track changes and get a FireAt once their last track is playing.
+type SleepTimer struct {
+	ID              string    `json:"id"`
+	UserID          string    `json:"user_id"`
+	DeviceID        string    `json:"device_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	FireAt          time.Time `json:"fire_at"`
+	Tracks          int       `json:"tracks,omitempty"`
+	TracksRemaining int       `json:"tracks_remaining,omitempty"`
+	LastTrackID     string    `json:"last_track_id,omitempty"`
+	FadeSeconds     int       `json:"fade_seconds,omitempty"`
+	Fading          bool      `json:"fading"`
+}
+
+func (t *SleepTimer) trackBased() bool {
+	return t.Tracks > 0
+}
+
+// SleepScheduler runs the logged-in user's sleep timers and persists them so they survive restarts.
+type SleepScheduler struct {
+	tokenMx *TokenManager
+	limits  *VolumeLimits
+	path    string
+	timers  map[string]*SleepTimer
+	fades   map[string]context.CancelFunc
+	mutx    sync.Mutex
+}
+
+func NewSleepScheduler(tokenMx *TokenManager, limits *VolumeLimits, path string) (*SleepScheduler, error) {
+	ss := &SleepScheduler{
+		tokenMx: tokenMx,
+		limits:  limits,
+		path:    path,
+		timers:  map[string]*SleepTimer{},
+		fades:   map[string]context.CancelFunc{},
+	}
+	var timers []*SleepTimer
+	if err := loadJSON(path, &timers); err != nil {
+		return nil, err
+	}
+	for _, timer := range timers {
+		// A fade interrupted by a restart is started again from the current volume.
+		timer.Fading = false
+		ss.timers[timer.ID] = timer
+	}
+	return ss, nil
+}
+
+// Start checks the timers in the background until ctx is cancelled.
+func (ss *SleepScheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(sleepTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ss.tick()
+			}
+		}
+	}()
+}
+
+// AfterDuration schedules a pause after d.
+func (ss *SleepScheduler) AfterDuration(userID, deviceID string, d time.Duration, fade time.Duration) (*SleepTimer, error) {
+	if d <= 0 {
+		return nil, fmt.Errorf("duration must be positive")
+	}
+	if fade > d {
+		return nil, fmt.Errorf("fade must not be longer than the timer")
+	}
+	timer := &SleepTimer{
+		ID:          newID(),
+		UserID:      userID,
+		DeviceID:    deviceID,
+		CreatedAt:   time.Now().UTC(),
+		FireAt:      time.Now().Add(d).UTC(),
+		FadeSeconds: int(fade / time.Second),
+	}
+	return timer, ss.add(timer)
+}
+
+// AfterTracks schedules a pause at the end of the current track (tracks = 1) or after tracks-1 further tracks.
+func (ss *SleepScheduler) AfterTracks(accessToken, userID, deviceID string, tracks int, fade time.Duration) (*SleepTimer, int, error) {
+	if tracks < 1 {
+		return nil, http.StatusBadRequest, fmt.Errorf("tracks must be at least 1")
+	}
+	current, status, err := GetCurrentPlayingTrack(accessToken)
+	if err != nil {
+		return nil, status, err
+	}
+	if current.Item == nil {
+		return nil, http.StatusConflict, fmt.Errorf("nothing is playing")
+	}
+	timer := &SleepTimer{
+		ID:              newID(),
+		UserID:          userID,
+		DeviceID:        deviceID,
+		CreatedAt:       time.Now().UTC(),
+		Tracks:          tracks,
+		TracksRemaining: tracks,
+		LastTrackID:     current.Item.ID,
+		FadeSeconds:     int(fade / time.Second),
+	}
+	if err := ss.add(timer); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return timer, http.StatusCreated, nil
+}
+
+func (ss *SleepScheduler) add(timer *SleepTimer) error {
+	ss.mutx.Lock()
+	defer ss.mutx.Unlock()
+
+	ss.timers[timer.ID] = timer
+	if err := ss.save(); err != nil {
+		delete(ss.timers, timer.ID)
+		return err
+	}
+	return nil
+}
+
+// List returns the user's pending timers in the order they were created.
+func (ss *SleepScheduler) List(userID string) []SleepTimer {
+	ss.mutx.Lock()
+	defer ss.mutx.Unlock()
+
+	timers := []SleepTimer{}
+	for _, timer := range ss.timers {
+		if timer.UserID == userID {
+			timers = append(timers, *timer)
+		}
+	}
+	slices.SortFunc(timers, func(a, b SleepTimer) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return timers
+}
+
+// Cancel removes a timer, stopping its fade if one is running, and reports whether it existed.
+func (ss *SleepScheduler) Cancel(userID, id string) (bool, error) {
+	ss.mutx.Lock()
+	defer ss.mutx.Unlock()
+
+	timer, ok := ss.timers[id]
+	if !ok || timer.UserID != userID {
+		return false, nil
+	}
+	delete(ss.timers, id)
+	if err := ss.save(); err != nil {
+		ss.timers[id] = timer
+		return false, err
+	}
+	if cancel, fading := ss.fades[id]; fading {
+		cancel()
+		delete(ss.fades, id)
+	}
+	return true, nil
+}
+
+func (ss *SleepScheduler) save() error {
+	timers := make([]*SleepTimer, 0, len(ss.timers))
+	for _, timer := range ss.timers {
+		timers = append(timers, timer)
+	}
+	slices.SortFunc(timers, func(a, b *SleepTimer) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return saveJSON(ss.path, timers)
+}
+
+func (ss *SleepScheduler) tick() {
+	accessToken, valid := ss.tokenMx.GetToken()
+	userID := ss.tokenMx.GetUser()
+	if !valid || userID == "" {
+		return
+	}
+
+	ss.mutx.Lock()
+	needsTrack := false
+	for _, timer := range ss.timers {
+		if timer.UserID == userID && timer.trackBased() && !timer.Fading {
+			needsTrack = true
+		}
+	}
+	ss.mutx.Unlock()
+
+	var current *CurrentTrackResponse
+	if needsTrack {
+		var err error
+		if current, _, err = GetCurrentPlayingTrack(accessToken); err != nil {
+			log.Printf("sleep timer: %v", err)
+			return
+		}
+	}
+
+	ss.mutx.Lock()
+	defer ss.mutx.Unlock()
+
+	now := time.Now()
+	changed := false
+	for _, timer := range ss.timers {
+		if timer.UserID != userID || timer.Fading {
+			continue
+		}
+		if timer.trackBased() {
+			changed = ss.followTracks(timer, current, now) || changed
+		}
+		if timer.FireAt.IsZero() {
+			continue
+		}
+		fade := time.Duration(timer.FadeSeconds) * time.Second
+		if now.Before(timer.FireAt.Add(-fade)) {
+			continue
+		}
+		fadeCtx, cancel := context.WithCancel(context.Background())
+		ss.fades[timer.ID] = cancel
+		timer.Fading = true
+		changed = true
+		go ss.fire(fadeCtx, accessToken, *timer, timer.FireAt.Sub(now))
+	}
+	if changed {
+		if err := ss.save(); err != nil {
+			log.Printf("sleep timer: %v", err)
+		}
+	}
+}
+
+// followTracks counts track changes for a track based timer and sets FireAt once its last track plays.
+func (ss *SleepScheduler) followTracks(timer *SleepTimer, current *CurrentTrackResponse, now time.Time) bool {
+	if current == nil || current.Item == nil {
+		return false
+	}
+	changed := false
+	if current.Item.ID != timer.LastTrackID {
+		timer.TracksRemaining--
+		timer.LastTrackID = current.Item.ID
+		changed = true
+	}
+	switch {
+	case timer.TracksRemaining <= 0:
+		timer.FireAt = now.UTC()
+	case timer.TracksRemaining == 1 && current.IsPlaying:
+		left := time.Duration(int64(current.Item.DurationMS)-current.ProgressMS) * time.Millisecond
+		timer.FireAt = now.Add(left - sleepTrackLead).UTC()
+	default:
+		timer.FireAt = time.Time{}
+	}
+	return changed
+}
+
+// fire fades out over untilFire, pauses, puts the volume back for the next time playback starts,
+// and removes the timer. A cancelled fade leaves playback running. The fade stops at the device's
+// minimum volume and the restored volume is capped to its limit.
+func (ss *SleepScheduler) fire(ctx context.Context, accessToken string, timer SleepTimer, untilFire time.Duration) {
+	defer func() {
+		ss.mutx.Lock()
+		defer ss.mutx.Unlock()
+
+		delete(ss.fades, timer.ID)
+		if _, ok := ss.timers[timer.ID]; ok {
+			delete(ss.timers, timer.ID)
+			if err := ss.save(); err != nil {
+				log.Printf("sleep timer: %v", err)
+			}
+		}
+	}()
+
+	originalVolume := -1
+	if timer.FadeSeconds > 0 && untilFire > 0 {
+		if state, _, err := GetPlayBack(accessToken); err == nil && state.Device != nil && state.Device.SupportsVolume {
+			originalVolume = ss.limits.Clamp(timer.UserID, state.Device.ID, int(state.Device.VolumePercent))
+			quietest := ss.limits.Clamp(timer.UserID, state.Device.ID, 0)
+			if _, err := FadeVolume(ctx, accessToken, timer.DeviceID, int(state.Device.VolumePercent), quietest, untilFire, CurveLinear); err != nil {
+				if ctx.Err() != nil {
+					SetPlaybackVolume(accessToken, timer.DeviceID, originalVolume)
+					return
+				}
+				log.Printf("sleep timer: fade failed: %v", err)
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if _, err := PausePlayback(accessToken, timer.DeviceID); err != nil {
+		log.Printf("sleep timer: %v", err)
+	}
+	if originalVolume >= 0 {
+		if _, err := SetPlaybackVolume(accessToken, timer.DeviceID, originalVolume); err != nil {
+			log.Printf("sleep timer: failed to restore volume: %v", err)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// StepResult reports the outcome of one API call made as part of a multi-step operation.
+type StepResult struct {
+	Step   string `json:"step"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func stepResult(step string, status int, err error) StepResult {
+	result := StepResult{Step: step, Status: status}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// PlaybackSnapshot is a saved copy of everything needed to resume playback where it was.
+type PlaybackSnapshot struct {
+	ID           string      `json:"id"`
+	Name         string      `json:"name,omitempty"`
+	UserID       string      `json:"user_id"`
+	CreatedAt    time.Time   `json:"created_at"`
+	ContextURI   string      `json:"context_uri,omitempty"`
+	Item         *Track      `json:"item"`
+	ProgressMS   uint64      `json:"progress_ms"`
+	IsPlaying    bool        `json:"is_playing"`
+	Device       *DeviceData `json:"device"`
+	ShuffleState bool        `json:"shuffle_state"`
+	RepeatState  string      `json:"repeat_state"`
+	Queue        []*Track    `json:"queue"`
+}
+
+// CaptureSnapshot reads the current playback state and queue into a new, unsaved snapshot.
+func CaptureSnapshot(accessToken string) (*PlaybackSnapshot, int, error) {
+	state, status, err := currentPlayback(accessToken)
+	if err != nil {
+		return nil, status, err
+	}
+	queue, status, err := GetUsersQueue(accessToken)
+	if err != nil {
+		return nil, status, err
+	}
+
+	snapshot := &PlaybackSnapshot{
+		ID:           newID(),
+		CreatedAt:    time.Now().UTC(),
+		Item:         state.Item,
+		ProgressMS:   state.ProgressMS,
+		IsPlaying:    state.IsPlaying,
+		Device:       state.Device,
+		ShuffleState: state.ShuffleState,
+		RepeatState:  state.RepeatState,
+		Queue:        queue.Queue,
+	}
+	if state.Context != nil {
+		snapshot.ContextURI = state.Context.URI
+	}
+	return snapshot, http.StatusOK, nil
+}
+
+// RestoreSnapshot reapplies a snapshot step by step: device, item and position, volume, shuffle, repeat,
+// and finally pauses again if the snapshot was paused. When restoreQueue is set the saved queue is re-added,
+// which duplicates anything still queued since Spotify's queue cannot be cleared.
+// The saved volume is capped to the device's current volume limit.
+// Failing steps are reported and the remaining steps still run; the returned bool is false if any step failed.
+func RestoreSnapshot(accessToken string, limits *VolumeLimits, snapshot *PlaybackSnapshot, restoreQueue bool) ([]StepResult, bool) {
+	var results []StepResult
+	ok := true
+	run := func(step string, status int, err error) {
+		results = append(results, stepResult(step, status, err))
+		if err != nil {
+			ok = false
+		}
+	}
+
+	deviceID := ""
+	if snapshot.Device != nil && snapshot.Device.ID != "" {
+		deviceID = snapshot.Device.ID
+		status, err := TransferPlayback(accessToken, deviceID, false)
+		run("transfer", status, err)
+	}
+	if snapshot.Item != nil {
+		opts := PlaybackOptions{URIs: []string{snapshot.Item.URI}}
+		if snapshot.ContextURI != "" {
+			opts = PlaybackOptions{ContextURI: snapshot.ContextURI, Offset: &PlaybackOffset{URI: snapshot.Item.URI}}
+		}
+		status, err := StartPlayback(accessToken, deviceID, opts)
+		run("play", status, err)
+		status, err = SeekPosition(accessToken, deviceID, int(snapshot.ProgressMS))
+		run("seek", status, err)
+	}
+	if snapshot.Device != nil && snapshot.Device.SupportsVolume {
+		volume := limits.Clamp(snapshot.UserID, deviceID, int(snapshot.Device.VolumePercent))
+		status, err := SetPlaybackVolume(accessToken, deviceID, volume)
+		run("volume", status, err)
+	}
+	status, err := ToggleShuffle(accessToken, deviceID, snapshot.ShuffleState)
+	run("shuffle", status, err)
+	if snapshot.RepeatState != "" {
+		status, err := ToggleRepeat(accessToken, deviceID, snapshot.RepeatState)
+		run("repeat", status, err)
+	}
+	if restoreQueue {
+		for _, track := range snapshot.Queue {
+			if track == nil || track.URI == "" {
+				continue
+			}
+			status, err := AddToQueue(accessToken, deviceID, track.URI)
+			run("queue "+track.URI, status, err)
+		}
+	}
+	if !snapshot.IsPlaying {
+		status, err := PausePlayback(accessToken, deviceID)
+		run("pause", status, err)
+	}
+	return results, ok
+}
+
+// SnapshotStore keeps playback snapshots in a JSON file.
+type SnapshotStore struct {
+	path      string
+	snapshots map[string]*PlaybackSnapshot
+	mutx      sync.RWMutex
+}
+
+func NewSnapshotStore(path string) (*SnapshotStore, error) {
+	ss := &SnapshotStore{path: path, snapshots: map[string]*PlaybackSnapshot{}}
+	var snapshots []*PlaybackSnapshot
+	if err := loadJSON(path, &snapshots); err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		ss.snapshots[snapshot.ID] = snapshot
+	}
+	return ss, nil
+}
+
+func (ss *SnapshotStore) Save(snapshot *PlaybackSnapshot) error {
+	ss.mutx.Lock()
+	defer ss.mutx.Unlock()
+
+	previous, existed := ss.snapshots[snapshot.ID]
+	ss.snapshots[snapshot.ID] = snapshot
+	if err := ss.persist(); err != nil {
+		if existed {
+			ss.snapshots[snapshot.ID] = previous
+		} else {
+			delete(ss.snapshots, snapshot.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Get returns the user's snapshot with the given ID.
+func (ss *SnapshotStore) Get(userID, id string) (*PlaybackSnapshot, bool) {
+	ss.mutx.RLock()
+	defer ss.mutx.RUnlock()
+
+	snapshot, ok := ss.snapshots[id]
+	if !ok || snapshot.UserID != userID {
+		return nil, false
+	}
+	return snapshot, true
+}
+
+// List returns the user's snapshots, newest first.
+func (ss *SnapshotStore) List(userID string) []*PlaybackSnapshot {
+	ss.mutx.RLock()
+	defer ss.mutx.RUnlock()
+
+	snapshots := []*PlaybackSnapshot{}
+	for _, snapshot := range ss.snapshots {
+		if snapshot.UserID == userID {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	slices.SortFunc(snapshots, func(a, b *PlaybackSnapshot) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return snapshots
+}
+
+// Delete removes the user's snapshot and reports whether it existed.
+func (ss *SnapshotStore) Delete(userID, id string) (bool, error) {
+	ss.mutx.Lock()
+	defer ss.mutx.Unlock()
+
+	snapshot, ok := ss.snapshots[id]
+	if !ok || snapshot.UserID != userID {
+		return false, nil
+	}
+	delete(ss.snapshots, id)
+	if err := ss.persist(); err != nil {
+		ss.snapshots[id] = snapshot
+		return false, err
+	}
+	return true, nil
+}
+
+func (ss *SnapshotStore) persist() error {
+	snapshots := make([]*PlaybackSnapshot, 0, len(ss.snapshots))
+	for _, snapshot := range ss.snapshots {
+		snapshots = append(snapshots, snapshot)
+	}
+	slices.SortFunc(snapshots, func(a, b *PlaybackSnapshot) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return saveJSON(ss.path, snapshots)
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"sort"
+	"time"
+)
+
+// RankedItem is one entry of a top tracks, artists or albums list.
+type RankedItem struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Plays       int    `json:"plays"`
+	ListeningMS int64  `json:"listening_ms"`
+}
+
+type Streak struct {
+	Days  int    `json:"days"`
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+// ListeningStats summarises a set of recorded plays.
+// HourOfDay is indexed by hour, DayOfWeek and Heatmap rows by time.Weekday (Sunday first).
+type ListeningStats struct {
+	From          *time.Time   `json:"from,omitempty"`
+	To            *time.Time   `json:"to,omitempty"`
+	TotalPlays    int          `json:"total_plays"`
+	ListeningMS   int64        `json:"listening_ms"`
+	TopTracks     []RankedItem `json:"top_tracks"`
+	TopArtists    []RankedItem `json:"top_artists"`
+	TopAlbums     []RankedItem `json:"top_albums"`
+	HourOfDay     [24]int      `json:"hour_of_day"`
+	DayOfWeek     [7]int       `json:"day_of_week"`
+	Heatmap       [7][24]int   `json:"heatmap"`
+	CurrentStreak Streak       `json:"current_streak"`
+	LongestStreak Streak       `json:"longest_streak"`
+}
+
+// ComputeStats aggregates records, keeping the top entries of each ranking.
+// Hours, weekdays and streak days are taken in loc; now decides whether the current streak is still alive.
+func ComputeStats(records []PlayRecord, top int, loc *time.Location, now time.Time) ListeningStats {
+	var stats ListeningStats
+	tracks := map[string]*RankedItem{}
+	artists := map[string]*RankedItem{}
+	albums := map[string]*RankedItem{}
+	days := map[string]bool{}
+
+	for _, record := range records {
+		playedAt := record.PlayedAt.In(loc)
+		stats.TotalPlays++
+		stats.HourOfDay[playedAt.Hour()]++
+		stats.DayOfWeek[playedAt.Weekday()]++
+		stats.Heatmap[playedAt.Weekday()][playedAt.Hour()]++
+		days[playedAt.Format(time.DateOnly)] = true
+
+		if record.Track == nil {
+			continue
+		}
+		duration := int64(record.Track.DurationMS)
+		stats.ListeningMS += duration
+		rank(tracks, record.Track.ID, record.Track.Name, duration)
+		rank(albums, record.Track.Album.ID, record.Track.Album.Name, duration)
+		for _, artist := range record.Track.Artists {
+			rank(artists, artist.ID, artist.Name, duration)
+		}
+	}
+
+	stats.TopTracks = topRanked(tracks, top)
+	stats.TopArtists = topRanked(artists, top)
+	stats.TopAlbums = topRanked(albums, top)
+	stats.CurrentStreak, stats.LongestStreak = streaks(days, now.In(loc))
+	return stats
+}
+
+func rank(ranking map[string]*RankedItem, id, name string, duration int64) {
+	if id == "" {
+		return
+	}
+	item, ok := ranking[id]
+	if !ok {
+		item = &RankedItem{ID: id, Name: name}
+		ranking[id] = item
+	}
+	item.Plays++
+	item.ListeningMS += duration
+}
+
+func topRanked(ranking map[string]*RankedItem, top int) []RankedItem {
+	items := make([]RankedItem, 0, len(ranking))
+	for _, item := range ranking {
+		items = append(items, *item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Plays != items[j].Plays {
+			return items[i].Plays > items[j].Plays
+		}
+		if items[i].ListeningMS != items[j].ListeningMS {
+			return items[i].ListeningMS > items[j].ListeningMS
+		}
+		return items[i].Name < items[j].Name
+	})
+	if top > 0 && len(items) > top {
+		items = items[:top]
+	}
+	return items
+}
+
+// streaks finds runs of consecutive days with at least one play. The current streak is
+// the run ending today, or yesterday if nothing has been played yet today.
+func streaks(days map[string]bool, now time.Time) (current, longest Streak) {
+	sorted := make([]string, 0, len(days))
+	for day := range days {
+		sorted = append(sorted, day)
+	}
+	sort.Strings(sorted)
+
+	var run Streak
+	var previous time.Time
+	for _, day := range sorted {
+		date, _ := time.Parse(time.DateOnly, day)
+		if run.Days > 0 && date.Equal(previous.AddDate(0, 0, 1)) {
+			run.Days++
+		} else {
+			run = Streak{Days: 1, Start: day}
+		}
+		run.End = day
+		previous = date
+		if run.Days > longest.Days {
+			longest = run
+		}
+	}
+
+	today := now.Format(time.DateOnly)
+	yesterday := now.AddDate(0, 0, -1).Format(time.DateOnly)
+	if run.End == today || run.End == yesterday {
+		current = run
+	}
+	return current, longest
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func statsTrack(t *testing.T, id, artistID string, durationMS int) *Track {
+	t.Helper()
+	var track Track
+	raw := fmt.Sprintf(`{"id":%[1]q,"name":%[1]q,"duration_ms":%[3]d,"album":{"id":"al-%[1]s","name":"al-%[1]s"},"artists":[{"id":%[2]q,"name":%[2]q}]}`, id, artistID, durationMS)
+	if err := json.Unmarshal([]byte(raw), &track); err != nil {
+		t.Fatal(err)
+	}
+	return &track
+}
+
+func TestStreaks(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		days        []string
+		wantCurrent Streak
+		wantLongest Streak
+	}{
+		{"no plays", nil, Streak{}, Streak{}},
+		{"today only", []string{"2024-03-10"}, Streak{1, "2024-03-10", "2024-03-10"}, Streak{1, "2024-03-10", "2024-03-10"}},
+		{"run ending yesterday is still current", []string{"2024-03-08", "2024-03-09"}, Streak{2, "2024-03-08", "2024-03-09"}, Streak{2, "2024-03-08", "2024-03-09"}},
+		{"run ending two days ago is broken", []string{"2024-03-07", "2024-03-08"}, Streak{}, Streak{2, "2024-03-07", "2024-03-08"}},
+		{"longest is earlier", []string{"2024-02-01", "2024-02-02", "2024-02-03", "2024-03-10"}, Streak{1, "2024-03-10", "2024-03-10"}, Streak{3, "2024-02-01", "2024-02-03"}},
+		{"across a month end", []string{"2024-02-28", "2024-02-29", "2024-03-01"}, Streak{}, Streak{3, "2024-02-28", "2024-03-01"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days := map[string]bool{}
+			for _, day := range tt.days {
+				days[day] = true
+			}
+			current, longest := streaks(days, now)
+			if current != tt.wantCurrent || longest != tt.wantLongest {
+				t.Errorf("streaks() = %+v, %+v, want %+v, %+v", current, longest, tt.wantCurrent, tt.wantLongest)
+			}
+		})
+	}
+}
+
+func TestComputeStats(t *testing.T) {
+	// Berlin is UTC+1 in January, so 23:30 UTC on a Saturday is 00:30 on Sunday there.
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	a := statsTrack(t, "a", "x", 200000)
+	b := statsTrack(t, "b", "y", 100000)
+	records := []PlayRecord{
+		{PlayedAt: time.Date(2024, 1, 6, 23, 30, 0, 0, time.UTC), Track: a},
+		{PlayedAt: time.Date(2024, 1, 7, 9, 0, 0, 0, time.UTC), Track: a},
+		{PlayedAt: time.Date(2024, 1, 7, 9, 10, 0, 0, time.UTC), Track: b},
+		{PlayedAt: time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC)},
+	}
+	stats := ComputeStats(records, 1, berlin, time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC))
+
+	if stats.TotalPlays != 4 || stats.ListeningMS != 500000 {
+		t.Errorf("totals = %d plays, %d ms, want 4 plays, 500000 ms", stats.TotalPlays, stats.ListeningMS)
+	}
+	if len(stats.TopTracks) != 1 || stats.TopTracks[0].ID != "a" || stats.TopTracks[0].Plays != 2 {
+		t.Errorf("TopTracks = %+v, want only a with 2 plays", stats.TopTracks)
+	}
+	if len(stats.TopArtists) != 1 || stats.TopArtists[0].ID != "x" {
+		t.Errorf("TopArtists = %+v, want only x", stats.TopArtists)
+	}
+	if got := stats.Heatmap[time.Sunday][0]; got != 1 {
+		t.Errorf("Heatmap[Sunday][0] = %d, want 1", got)
+	}
+	if got := stats.Heatmap[time.Sunday][10]; got != 2 {
+		t.Errorf("Heatmap[Sunday][10] = %d, want 2", got)
+	}
+	if got := stats.Heatmap[time.Saturday][23]; got != 0 {
+		t.Errorf("Heatmap[Saturday][23] = %d, want 0", got)
+	}
+	if stats.HourOfDay[10] != 3 || stats.DayOfWeek[time.Sunday] != 3 || stats.DayOfWeek[time.Monday] != 1 {
+		t.Errorf("HourOfDay[10] = %d, DayOfWeek = %v", stats.HourOfDay[10], stats.DayOfWeek)
+	}
+	want := Streak{Days: 2, Start: "2024-01-07", End: "2024-01-08"}
+	if stats.CurrentStreak != want || stats.LongestStreak != want {
+		t.Errorf("streaks = %+v, %+v, want %+v", stats.CurrentStreak, stats.LongestStreak, want)
+	}
+}
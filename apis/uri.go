@@ -0,0 +1,44 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var spotifyIDPattern = regexp.MustCompile(`^[0-9A-Za-z]{22}$`)
+
+// ParseSpotifyURI splits a URI such as spotify:track:<id> into its type and ID.
+// Legacy playlist URIs of the form spotify:user:<user>:playlist:<id> are reported as playlists.
+func ParseSpotifyURI(uri string) (string, string, error) {
+	parts := strings.Split(uri, ":")
+	if len(parts) == 5 && parts[0] == "spotify" && parts[1] == "user" && parts[3] == "playlist" {
+		parts = []string{"spotify", "playlist", parts[4]}
+	}
+	if len(parts) != 3 || parts[0] != "spotify" || parts[1] == "" {
+		return "", "", fmt.Errorf("%q is not a Spotify URI", uri)
+	}
+	if !spotifyIDPattern.MatchString(parts[2]) {
+		return "", "", fmt.Errorf("%q does not contain a valid Spotify ID", uri)
+	}
+	return parts[1], parts[2], nil
+}
+
+// ValidateSpotifyURI checks that uri is a Spotify URI of one of the given types.
+func ValidateSpotifyURI(uri string, types ...string) error {
+	kind, _, err := ParseSpotifyURI(uri)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(types, kind) {
+		return fmt.Errorf("%q is a %s URI, expected %s", uri, kind, strings.Join(types, " or "))
+	}
+	return nil
+}
+
+// ContextURITypes are the URI types Spotify can play as a context; PlayableURITypes can be played or queued on their own.
+var (
+	ContextURITypes  = []string{"album", "artist", "playlist", "show"}
+	PlayableURITypes = []string{"track", "episode"}
+)
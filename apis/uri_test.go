@@ -0,0 +1,37 @@
+package api
+
+import "testing"
+
+const (
+	testTrackURI    = "spotify:track:4uLU6hMCjMI75M1A2tKUQC"
+	testEpisodeURI  = "spotify:episode:512ojhOuo1ktJprKbVcKyQ"
+	testAlbumURI    = "spotify:album:4aawyAB9vmqN3uQ7FjRGTy"
+	testPlaylistURI = "spotify:playlist:37i9dQZF1DXcBWIGoYBM5M"
+)
+
+func TestParseSpotifyURI(t *testing.T) {
+	tests := []struct {
+		uri      string
+		wantKind string
+		wantID   string
+		wantErr  bool
+	}{
+		{testTrackURI, "track", "4uLU6hMCjMI75M1A2tKUQC", false},
+		{testPlaylistURI, "playlist", "37i9dQZF1DXcBWIGoYBM5M", false},
+		{"spotify:user:someone:playlist:37i9dQZF1DXcBWIGoYBM5M", "playlist", "37i9dQZF1DXcBWIGoYBM5M", false},
+		{"", "", "", true},
+		{"spotify:track", "", "", true},
+		{"spotify::4uLU6hMCjMI75M1A2tKUQC", "", "", true},
+		{"spotfy:track:4uLU6hMCjMI75M1A2tKUQC", "", "", true},
+		{"spotify:track:4uLU6hMCjMI75M1A2tKUQ", "", "", true},
+		{"spotify:track:4uLU6hMCjMI75M1A2tKUQ!", "", "", true},
+		{"https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC", "", "", true},
+		{"spotify:user:someone:album:4aawyAB9vmqN3uQ7FjRGTy", "", "", true},
+	}
+	for _, tt := range tests {
+		kind, id, err := ParseSpotifyURI(tt.uri)
+		if (err != nil) != tt.wantErr || kind != tt.wantKind || id != tt.wantID {
+			t.Errorf("ParseSpotifyURI(%q) = %q, %q, %v, want %q, %q, error %v", tt.uri, kind, id, err, tt.wantKind, tt.wantID, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// VolumeLimit keeps a device's volume between Min and Max, whoever sets it.
+type VolumeLimit struct {
+	DeviceID   string `json:"device_id"`
+	DeviceName string `json:"device_name,omitempty"`
+	Min        int    `json:"min"`
+	Max        int    `json:"max"`
+}
+
+// VolumeChange is the outcome of SetVolume. Limited is set when the requested volume was capped.
+type VolumeChange struct {
+	DeviceID string       `json:"device_id"`
+	Previous int          `json:"previous"`
+	Volume   int          `json:"volume"`
+	Limited  bool         `json:"limited"`
+	Limit    *VolumeLimit `json:"limit,omitempty"`
+}
+
+// VolumeLimits keeps each user's per-device volume limits in a JSON file.
+type VolumeLimits struct {
+	path   string
+	limits map[string]map[string]VolumeLimit
+	mutx   sync.RWMutex
+}
+
+func NewVolumeLimits(path string) (*VolumeLimits, error) {
+	vl := &VolumeLimits{path: path, limits: map[string]map[string]VolumeLimit{}}
+	if err := loadJSON(path, &vl.limits); err != nil {
+		return nil, err
+	}
+	return vl, nil
+}
+
+// List returns the user's limits ordered by device name.
+func (vl *VolumeLimits) List(userID string) []VolumeLimit {
+	vl.mutx.RLock()
+	defer vl.mutx.RUnlock()
+
+	limits := []VolumeLimit{}
+	for _, limit := range vl.limits[userID] {
+		limits = append(limits, limit)
+	}
+	slices.SortFunc(limits, func(a, b VolumeLimit) int { return strings.Compare(a.DeviceName, b.DeviceName) })
+	return limits
+}
+
+// Set stores the limit for a device, replacing any previous one.
+func (vl *VolumeLimits) Set(userID string, limit VolumeLimit) (int, error) {
+	if limit.DeviceID == "" {
+		return http.StatusBadRequest, fmt.Errorf("device_id is required")
+	}
+	if limit.Min < 0 || limit.Max > 100 || limit.Min > limit.Max {
+		return http.StatusBadRequest, fmt.Errorf("limits must satisfy 0 <= min <= max <= 100")
+	}
+	vl.mutx.Lock()
+	defer vl.mutx.Unlock()
+
+	if vl.limits[userID] == nil {
+		vl.limits[userID] = map[string]VolumeLimit{}
+	}
+	previous, existed := vl.limits[userID][limit.DeviceID]
+	vl.limits[userID][limit.DeviceID] = limit
+	if err := saveJSON(vl.path, vl.limits); err != nil {
+		if existed {
+			vl.limits[userID][limit.DeviceID] = previous
+		} else {
+			delete(vl.limits[userID], limit.DeviceID)
+		}
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// Delete removes a device's limit and reports whether there was one.
+func (vl *VolumeLimits) Delete(userID, deviceID string) (bool, error) {
+	vl.mutx.Lock()
+	defer vl.mutx.Unlock()
+
+	limit, ok := vl.limits[userID][deviceID]
+	if !ok {
+		return false, nil
+	}
+	delete(vl.limits[userID], deviceID)
+	if err := saveJSON(vl.path, vl.limits); err != nil {
+		vl.limits[userID][deviceID] = limit
+		return false, err
+	}
+	return true, nil
+}
+
+func (vl *VolumeLimits) limit(userID, deviceID string) (VolumeLimit, bool) {
+	vl.mutx.RLock()
+	defer vl.mutx.RUnlock()
+
+	limit, ok := vl.limits[userID][deviceID]
+	return limit, ok
+}
+
+// Clamp keeps volume within 0 to 100 and the limit of deviceID, if it has one.
+// Every path that sets a volume on the user's behalf passes its target through Clamp first.
+func (vl *VolumeLimits) Clamp(userID, deviceID string, volume int) int {
+	volume = min(max(volume, 0), 100)
+	if limit, ok := vl.limit(userID, deviceID); ok {
+		volume = min(max(volume, limit.Min), limit.Max)
+	}
+	return volume
+}
+
+// SetVolume sets the volume of deviceID, or of the active device when deviceID is empty. With relative set,
+// volume is a step from the device's current volume. The result is kept within 0 to 100 and the device's limit.
+// Devices that do not support volume control are rejected without changing anything.
+func (vl *VolumeLimits) SetVolume(accessToken, userID, deviceID string, volume int, relative bool) (*VolumeChange, int, error) {
+	if !relative && (volume < 0 || volume > 100) {
+		return nil, http.StatusBadRequest, fmt.Errorf("volume must be between 0 and 100")
+	}
+	device, status, err := VolumeDevice(accessToken, deviceID)
+	if err != nil {
+		return nil, status, err
+	}
+	return vl.setDeviceVolume(accessToken, userID, device, volume, relative)
+}
+
+// setDeviceVolume is SetVolume for a device already looked up and known to support volume control.
+func (vl *VolumeLimits) setDeviceVolume(accessToken, userID string, device *DeviceData, volume int, relative bool) (*VolumeChange, int, error) {
+	change := &VolumeChange{DeviceID: device.ID, Previous: int(device.VolumePercent), Volume: volume}
+	if relative {
+		change.Volume = min(max(change.Previous+volume, 0), 100)
+	}
+	if limit, ok := vl.limit(userID, device.ID); ok {
+		change.Limit = &limit
+		if capped := min(max(change.Volume, limit.Min), limit.Max); capped != change.Volume {
+			change.Volume, change.Limited = capped, true
+		}
+	}
+	status, err := SetPlaybackVolume(accessToken, device.ID, change.Volume)
+	if err != nil {
+		return nil, status, err
+	}
+	return change, status, nil
+}
@@ -0,0 +1,312 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	EventTest = "test"
+
+	webhookMaxAttempts  = 5
+	webhookFirstBackoff = time.Second
+	webhookLogSize      = 100
+)
+
+// WebhookEvents are the playback events a webhook can subscribe to.
+var WebhookEvents = []string{EventTrackChanged, EventDeviceChanged, EventPaused, EventResumed}
+
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery records one attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	ID         string    `json:"id"`
+	WebhookID  string    `json:"webhook_id"`
+	Event      string    `json:"event"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Time       time.Time `json:"time"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+type webhookPayload struct {
+	ID    string            `json:"id"`
+	Event string            `json:"event"`
+	Time  time.Time         `json:"time"`
+	State *PlayBackResponse `json:"state"`
+}
+
+// WebhookManager stores the registered webhooks and POSTs playback changes to them.
+// Each request carries an X-Blastboom-Signature header holding "sha256=" and the hex HMAC-SHA256 of
+// the X-Blastboom-Timestamp header value, a ".", and the raw body, keyed with the webhook's secret.
+type WebhookManager struct {
+	path        string
+	watcher     *PlaybackWatcher
+	client      *http.Client
+	hooks       map[string]*Webhook
+	deliveries  map[string][]WebhookDelivery
+	unsubscribe func()
+	mutx        sync.Mutex
+}
+
+// NewWebhookManager loads the webhooks stored at path and starts listening to watcher if any exist.
+func NewWebhookManager(path string, watcher *PlaybackWatcher) (*WebhookManager, error) {
+	wm := &WebhookManager{
+		path:       path,
+		watcher:    watcher,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		hooks:      map[string]*Webhook{},
+		deliveries: map[string][]WebhookDelivery{},
+	}
+	var hooks []*Webhook
+	if err := loadJSON(path, &hooks); err != nil {
+		return nil, err
+	}
+	for _, hook := range hooks {
+		wm.hooks[hook.ID] = hook
+	}
+	wm.mutx.Lock()
+	wm.listen()
+	wm.mutx.Unlock()
+	return wm, nil
+}
+
+// Add registers a webhook. A random secret is generated when secret is empty and all events are
+// delivered when events is empty. The returned webhook is the only place the secret is exposed.
+func (wm *WebhookManager) Add(hookURL, secret string, events []string) (*Webhook, int, error) {
+	parsed, err := url.Parse(hookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("url must be an absolute http or https URL")
+	}
+	for _, event := range events {
+		if !slices.Contains(WebhookEvents, event) {
+			return nil, http.StatusBadRequest, fmt.Errorf("unknown event %q, expected one of %v", event, WebhookEvents)
+		}
+	}
+	if len(events) == 0 {
+		events = WebhookEvents
+	}
+	if secret == "" {
+		secret = newID() + newID()
+	}
+
+	wm.mutx.Lock()
+	defer wm.mutx.Unlock()
+
+	hook := &Webhook{ID: newID(), URL: hookURL, Secret: secret, Events: events, CreatedAt: time.Now().UTC()}
+	wm.hooks[hook.ID] = hook
+	if err := wm.save(); err != nil {
+		delete(wm.hooks, hook.ID)
+		return nil, http.StatusInternalServerError, err
+	}
+	wm.listen()
+	created := *hook
+	return &created, http.StatusCreated, nil
+}
+
+// List returns the registered webhooks with their secrets removed.
+func (wm *WebhookManager) List() []Webhook {
+	wm.mutx.Lock()
+	defer wm.mutx.Unlock()
+
+	hooks := make([]Webhook, 0, len(wm.hooks))
+	for _, hook := range wm.hooks {
+		listed := *hook
+		listed.Secret = ""
+		hooks = append(hooks, listed)
+	}
+	slices.SortFunc(hooks, func(a, b Webhook) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return hooks
+}
+
+// Remove deletes a webhook and reports whether it existed.
+func (wm *WebhookManager) Remove(id string) (bool, error) {
+	wm.mutx.Lock()
+	defer wm.mutx.Unlock()
+
+	hook, ok := wm.hooks[id]
+	if !ok {
+		return false, nil
+	}
+	delete(wm.hooks, id)
+	if err := wm.save(); err != nil {
+		wm.hooks[id] = hook
+		return false, err
+	}
+	delete(wm.deliveries, id)
+	wm.listen()
+	return true, nil
+}
+
+// Deliveries returns the most recent delivery attempts of a webhook, oldest first.
+func (wm *WebhookManager) Deliveries(id string) ([]WebhookDelivery, bool) {
+	wm.mutx.Lock()
+	defer wm.mutx.Unlock()
+
+	if _, ok := wm.hooks[id]; !ok {
+		return nil, false
+	}
+	return slices.Clone(wm.deliveries[id]), true
+}
+
+// Test synchronously sends a test event with the current playback state to a webhook, without retries.
+func (wm *WebhookManager) Test(id string) (*WebhookDelivery, bool) {
+	wm.mutx.Lock()
+	hook, ok := wm.hooks[id]
+	wm.mutx.Unlock()
+	if !ok {
+		return nil, false
+	}
+	delivery := wm.attempt(*hook, webhookPayload{
+		ID:    newID(),
+		Event: EventTest,
+		Time:  time.Now().UTC(),
+		State: wm.watcher.Current(),
+	}, 1)
+	return &delivery, true
+}
+
+func (wm *WebhookManager) save() error {
+	hooks := make([]*Webhook, 0, len(wm.hooks))
+	for _, hook := range wm.hooks {
+		hooks = append(hooks, hook)
+	}
+	slices.SortFunc(hooks, func(a, b *Webhook) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return saveJSON(wm.path, hooks)
+}
+
+// listen keeps a watcher subscription open exactly while webhooks are registered. Callers hold wm.mutx.
+func (wm *WebhookManager) listen() {
+	if len(wm.hooks) == 0 && wm.unsubscribe != nil {
+		wm.unsubscribe()
+		wm.unsubscribe = nil
+		return
+	}
+	if len(wm.hooks) > 0 && wm.unsubscribe == nil {
+		events, unsubscribe := wm.watcher.Subscribe()
+		wm.unsubscribe = unsubscribe
+		go func() {
+			for event := range events {
+				wm.dispatch(event)
+			}
+		}()
+	}
+}
+
+func (wm *WebhookManager) dispatch(event PlaybackEvent) {
+	wm.mutx.Lock()
+	var targets []Webhook
+	for _, hook := range wm.hooks {
+		if slices.Contains(hook.Events, event.Type) {
+			targets = append(targets, *hook)
+		}
+	}
+	wm.mutx.Unlock()
+
+	payload := webhookPayload{ID: newID(), Event: event.Type, Time: event.Time, State: event.State}
+	for _, hook := range targets {
+		go wm.deliver(hook, payload)
+	}
+}
+
+// deliver retries a payload with exponential backoff until the receiver answers with a 2xx status.
+func (wm *WebhookManager) deliver(hook Webhook, payload webhookPayload) {
+	backoff := webhookFirstBackoff
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		if delivery := wm.attempt(hook, payload, attempt); delivery.Error == "" {
+			return
+		}
+		wm.mutx.Lock()
+		_, registered := wm.hooks[hook.ID]
+		wm.mutx.Unlock()
+		if !registered || attempt == webhookMaxAttempts {
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (wm *WebhookManager) attempt(hook Webhook, payload webhookPayload, attempt int) WebhookDelivery {
+	delivery := WebhookDelivery{
+		ID:        payload.ID,
+		WebhookID: hook.ID,
+		Event:     payload.Event,
+		Attempt:   attempt,
+		Time:      time.Now().UTC(),
+	}
+	defer wm.record(&delivery)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		delivery.Error = fmt.Sprintf("failed to marshal request body: %v", err)
+		return delivery
+	}
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewBuffer(body))
+	if err != nil {
+		delivery.Error = fmt.Sprintf("failed to create request: %v", err)
+		return delivery
+	}
+	timestamp := strconv.FormatInt(delivery.Time.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Blastboom-Event", payload.Event)
+	req.Header.Set("X-Blastboom-Delivery", payload.ID)
+	req.Header.Set("X-Blastboom-Timestamp", timestamp)
+	req.Header.Set("X-Blastboom-Signature", "sha256="+SignWebhook(hook.Secret, timestamp, body))
+
+	resp, err := wm.client.Do(req)
+	delivery.DurationMS = time.Since(delivery.Time).Milliseconds()
+	if err != nil {
+		delivery.Error = fmt.Sprintf("failed to make request: %v", err)
+		return delivery
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		delivery.Error = fmt.Sprintf("receiver answered %s", resp.Status)
+	}
+	return delivery
+}
+
+func (wm *WebhookManager) record(delivery *WebhookDelivery) {
+	wm.mutx.Lock()
+	defer wm.mutx.Unlock()
+
+	if _, ok := wm.hooks[delivery.WebhookID]; !ok {
+		return
+	}
+	entries := append(wm.deliveries[delivery.WebhookID], *delivery)
+	if len(entries) > webhookLogSize {
+		entries = entries[len(entries)-webhookLogSize:]
+	}
+	wm.deliveries[delivery.WebhookID] = entries
+}
+
+// SignWebhook computes the hex HMAC-SHA256 signature receivers use to verify a delivery.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,33 @@
+package api
+
+import "testing"
+
+func TestSignWebhook(t *testing.T) {
+	// The expected signatures are HMAC-SHA256 over "<timestamp>.<body>", computed independently.
+	tests := []struct {
+		secret    string
+		timestamp string
+		body      string
+		want      string
+	}{
+		{"secret", "1700000000", `{"event":"paused"}`, "3af5f466bda5d24d8217f090f74e7a10e87fc0e66628de55e6791c0987c02097"},
+		{"s3cr3t", "1700000001", `{"event":"paused"}`, "2e4fe4eaf2a3246106ce7aba62b923585cb24b9308c27295a50b2cc2fa8e3eb0"},
+		{"", "0", "", "b849d5a581847b281957065739df36df2463d1977ea8d6e1e4e6cf33fadc68c3"},
+	}
+	for _, tt := range tests {
+		if got := SignWebhook(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+			t.Errorf("SignWebhook(%q, %q, %q) = %s, want %s", tt.secret, tt.timestamp, tt.body, got, tt.want)
+		}
+	}
+
+	base := SignWebhook("secret", "1700000000", []byte(`{"event":"paused"}`))
+	for name, got := range map[string]string{
+		"secret":    SignWebhook("Secret", "1700000000", []byte(`{"event":"paused"}`)),
+		"timestamp": SignWebhook("secret", "1700000001", []byte(`{"event":"paused"}`)),
+		"body":      SignWebhook("secret", "1700000000", []byte(`{"event":"resumed"}`)),
+	} {
+		if got == base {
+			t.Errorf("changing the %s did not change the signature", name)
+		}
+	}
+}
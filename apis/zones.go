@@ -0,0 +1,250 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxZoneDevices = 20
+
+var zoneNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Zone is a named group of devices that pause, volume and transfer commands are sent to together.
+type Zone struct {
+	Name      string    `json:"name"`
+	DeviceIDs []string  `json:"device_ids"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ZoneResult is the outcome of a zone command on one of its devices. Skipped says why the command was
+// not sent to the device.
+type ZoneResult struct {
+	DeviceID string        `json:"device_id"`
+	Status   int           `json:"status,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Skipped  string        `json:"skipped,omitempty"`
+	Volume   *VolumeChange `json:"volume,omitempty"`
+}
+
+// Zones keeps each user's zones in a JSON file and fans commands out to their devices.
+type Zones struct {
+	devices *DeviceResolver
+	limits  *VolumeLimits
+	path    string
+	zones   map[string]map[string]*Zone
+	mutx    sync.RWMutex
+}
+
+func NewZones(devices *DeviceResolver, limits *VolumeLimits, path string) (*Zones, error) {
+	z := &Zones{devices: devices, limits: limits, path: path, zones: map[string]map[string]*Zone{}}
+	if err := loadJSON(path, &z.zones); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// List returns the user's zones ordered by name.
+func (z *Zones) List(userID string) []*Zone {
+	z.mutx.RLock()
+	defer z.mutx.RUnlock()
+
+	zones := []*Zone{}
+	for _, zone := range z.zones[userID] {
+		zones = append(zones, zone)
+	}
+	slices.SortFunc(zones, func(a, b *Zone) int { return strings.Compare(a.Name, b.Name) })
+	return zones
+}
+
+// Get returns the user's zone with the given name.
+func (z *Zones) Get(userID, name string) (*Zone, bool) {
+	z.mutx.RLock()
+	defer z.mutx.RUnlock()
+
+	zone, ok := z.zones[userID][name]
+	return zone, ok
+}
+
+// Set stores a zone under name, replacing any previous one, and reports whether it replaced one.
+// Device names and aliases are resolved to IDs like everywhere else, so those devices must be available.
+// Device IDs come first in the zone's order, then the named devices; repeated devices are dropped.
+func (z *Zones) Set(accessToken, userID, name string, deviceIDs, deviceNames []string) (*Zone, bool, int, error) {
+	if !zoneNamePattern.MatchString(name) {
+		return nil, false, http.StatusBadRequest, fmt.Errorf("zone names are 1 to 64 letters, digits, dashes or underscores")
+	}
+	ids := []string{}
+	for _, id := range deviceIDs {
+		if id == "" {
+			return nil, false, http.StatusBadRequest, fmt.Errorf("device IDs must not be empty")
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(deviceNames) > 0 {
+		devices, status, err := GetDevices(accessToken)
+		if err != nil {
+			return nil, false, status, err
+		}
+		for _, deviceName := range deviceNames {
+			device, status, err := z.devices.findIn(devices.Devices, userID, deviceName)
+			if err != nil {
+				return nil, false, status, err
+			}
+			if !slices.Contains(ids, device.ID) {
+				ids = append(ids, device.ID)
+			}
+		}
+	}
+	if len(ids) == 0 || len(ids) > maxZoneDevices {
+		return nil, false, http.StatusBadRequest, fmt.Errorf("a zone needs between 1 and %d devices", maxZoneDevices)
+	}
+	zone := &Zone{Name: name, DeviceIDs: ids, UpdatedAt: time.Now().UTC()}
+
+	z.mutx.Lock()
+	defer z.mutx.Unlock()
+
+	if z.zones[userID] == nil {
+		z.zones[userID] = map[string]*Zone{}
+	}
+	previous, existed := z.zones[userID][name]
+	z.zones[userID][name] = zone
+	if err := saveJSON(z.path, z.zones); err != nil {
+		if existed {
+			z.zones[userID][name] = previous
+		} else {
+			delete(z.zones[userID], name)
+		}
+		return nil, false, http.StatusInternalServerError, err
+	}
+	return zone, existed, http.StatusOK, nil
+}
+
+// Delete removes the user's zone and reports whether it existed.
+func (z *Zones) Delete(userID, name string) (bool, error) {
+	z.mutx.Lock()
+	defer z.mutx.Unlock()
+
+	zone, ok := z.zones[userID][name]
+	if !ok {
+		return false, nil
+	}
+	delete(z.zones[userID], name)
+	if err := saveJSON(z.path, z.zones); err != nil {
+		z.zones[userID][name] = zone
+		return false, err
+	}
+	return true, nil
+}
+
+// Pause pauses the zone's device that is playing. Spotify plays on one device at a time, so the
+// unavailable and idle devices are reported as skipped. The returned bool is false if pausing failed.
+func (z *Zones) Pause(accessToken string, zone *Zone) ([]ZoneResult, int, bool, error) {
+	devices, status, err := GetDevices(accessToken)
+	if err != nil {
+		return nil, status, false, err
+	}
+	results := make([]ZoneResult, 0, len(zone.DeviceIDs))
+	ok := true
+	for _, deviceID := range zone.DeviceIDs {
+		i := slices.IndexFunc(devices.Devices, func(device DeviceData) bool { return device.ID == deviceID })
+		switch {
+		case i < 0:
+			results = append(results, ZoneResult{DeviceID: deviceID, Skipped: "device is not available"})
+		case !devices.Devices[i].IsActive:
+			results = append(results, ZoneResult{DeviceID: deviceID, Skipped: "device is not playing"})
+		default:
+			status, err := PausePlayback(accessToken, deviceID)
+			results = append(results, zoneResult(deviceID, status, err))
+			ok = ok && err == nil
+		}
+	}
+	return results, http.StatusOK, ok, nil
+}
+
+// SetVolume sets the volume of every device in the zone, each kept within its own volume limit.
+// The device list is fetched once for the whole zone.
+func (z *Zones) SetVolume(accessToken, userID string, zone *Zone, volume int) ([]ZoneResult, int, bool, error) {
+	devices, status, err := GetDevices(accessToken)
+	if err != nil {
+		return nil, status, false, err
+	}
+	results, ok := fanOut(zone, func(deviceID string) ZoneResult {
+		i := slices.IndexFunc(devices.Devices, func(device DeviceData) bool { return device.ID == deviceID })
+		if i < 0 {
+			return zoneResult(deviceID, http.StatusNotFound, fmt.Errorf("device %q is not available", deviceID))
+		}
+		device := &devices.Devices[i]
+		if !device.SupportsVolume {
+			return zoneResult(deviceID, http.StatusConflict, fmt.Errorf("device %q does not support volume control", device.Name))
+		}
+		change, status, err := z.limits.setDeviceVolume(accessToken, userID, device, volume, false)
+		result := zoneResult(deviceID, status, err)
+		result.Volume = change
+		return result
+	})
+	return results, http.StatusOK, ok, nil
+}
+
+// Transfer moves playback to the zone's first available device, in the zone's order. Spotify plays on
+// one device at a time, so the other devices are reported as skipped. When a transfer fails the next
+// available device is tried. The returned bool is false if no device took playback.
+func (z *Zones) Transfer(accessToken string, zone *Zone, play bool) ([]ZoneResult, int, bool, error) {
+	devices, status, err := GetDevices(accessToken)
+	if err != nil {
+		return nil, status, false, err
+	}
+	results := make([]ZoneResult, 0, len(zone.DeviceIDs))
+	target := ""
+	for _, deviceID := range zone.DeviceIDs {
+		switch {
+		case target != "":
+			results = append(results, ZoneResult{DeviceID: deviceID, Skipped: "playback moved to " + target})
+		case !slices.ContainsFunc(devices.Devices, func(device DeviceData) bool { return device.ID == deviceID }):
+			results = append(results, ZoneResult{DeviceID: deviceID, Skipped: "device is not available"})
+		default:
+			status, err := TransferPlayback(accessToken, deviceID, play)
+			results = append(results, zoneResult(deviceID, status, err))
+			if err == nil {
+				target = deviceID
+			}
+		}
+	}
+	return results, http.StatusOK, target != "", nil
+}
+
+// fanOut runs command for all of the zone's devices at once and collects the results in the zone's order.
+// The returned bool is false if any device failed.
+func fanOut(zone *Zone, command func(deviceID string) ZoneResult) ([]ZoneResult, bool) {
+	results := make([]ZoneResult, len(zone.DeviceIDs))
+	var wg sync.WaitGroup
+	for i, deviceID := range zone.DeviceIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = command(deviceID)
+		}()
+	}
+	wg.Wait()
+
+	ok := true
+	for _, result := range results {
+		if result.Error != "" {
+			ok = false
+		}
+	}
+	return results, ok
+}
+
+func zoneResult(deviceID string, status int, err error) ZoneResult {
+	result := ZoneResult{DeviceID: deviceID, Status: status}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFanOut(t *testing.T) {
+	tests := []struct {
+		name    string
+		devices []string
+		failing string
+		wantOK  bool
+	}{
+		{"all succeed", []string{"a", "b", "c"}, "", true},
+		{"one fails", []string{"a", "b", "c"}, "b", false},
+		{"single device", []string{"a"}, "", true},
+		{"empty zone", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, ok := fanOut(&Zone{DeviceIDs: tt.devices}, func(deviceID string) ZoneResult {
+				if deviceID == tt.failing {
+					return zoneResult(deviceID, http.StatusNotFound, fmt.Errorf("device %q is not available", deviceID))
+				}
+				return zoneResult(deviceID, http.StatusNoContent, nil)
+			})
+			if ok != tt.wantOK {
+				t.Errorf("fanOut() ok = %v, want %v", ok, tt.wantOK)
+			}
+			var got []string
+			for _, result := range results {
+				got = append(got, result.DeviceID)
+				if (result.Error != "") != (result.DeviceID == tt.failing) {
+					t.Errorf("result for %s = %+v", result.DeviceID, result)
+				}
+			}
+			if !slices.Equal(got, tt.devices) {
+				t.Errorf("results are for %v, want %v in zone order", got, tt.devices)
+			}
+		})
+	}
+}
+
+func TestFanOutRunsDevicesAtOnce(t *testing.T) {
+	devices := []string{"a", "b", "c", "d"}
+	var started sync.WaitGroup
+	started.Add(len(devices))
+	all := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(all)
+	}()
+	// Every command waits for all of them to start, which only happens when they run concurrently.
+	_, ok := fanOut(&Zone{DeviceIDs: devices}, func(deviceID string) ZoneResult {
+		started.Done()
+		select {
+		case <-all:
+			return zoneResult(deviceID, http.StatusNoContent, nil)
+		case <-time.After(5 * time.Second):
+			return zoneResult(deviceID, http.StatusGatewayTimeout, fmt.Errorf("ran alone"))
+		}
+	})
+	if !ok {
+		t.Error("fanOut() ran the devices one after another")
+	}
+}
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"context"
+	"log"
+	"time"
+
 	"github.com/gin-gonic/gin"
 
 	"blastboom/webservice/v1"
@@ -10,25 +14,160 @@ import (
 
 func main() {
 	tokenManager := api.NewTokenManager()
+	historyStore, err := api.NewHistoryStore(api.DataPath("history.jsonl"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	historyRecorder := api.NewHistoryRecorder(tokenManager, historyStore, 15*time.Minute)
+	historyRecorder.Start(context.Background())
+	playbackWatcher := api.NewPlaybackWatcher(tokenManager, 3*time.Second)
+	volumeFader := api.NewVolumeFader()
+	volumeLimits, err := api.NewVolumeLimits(api.DataPath("volume_limits.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	snapshots, err := api.NewSnapshotStore(api.DataPath("snapshots.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	deviceResolver, err := api.NewDeviceResolver(api.DataPath("device_aliases.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	preferredDevices, err := api.NewPreferredDevices(deviceResolver, api.DataPath("preferred_devices.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	sleepScheduler, err := api.NewSleepScheduler(tokenManager, volumeLimits, api.DataPath("sleep_timers.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	sleepScheduler.Start(context.Background())
+	alarms, err := api.NewAlarmScheduler(tokenManager, deviceResolver, volumeLimits, volumeFader, api.DataPath("alarms.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	alarms.Start(context.Background())
+	managedQueues, err := api.NewManagedQueues(tokenManager, api.DataPath("managed_queues.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	managedQueues.Start(context.Background())
+	skipRules, err := api.NewSkipRuleWatcher(tokenManager, api.DataPath("skip_rules.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	skipRules.Start(context.Background())
+	macros, err := api.NewMacros(deviceResolver, volumeLimits, api.DataPath("macros.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	zones, err := api.NewZones(deviceResolver, volumeLimits, api.DataPath("zones.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	parties, err := api.NewPartyManager(tokenManager, api.DataPath("parties.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	parties.Start(context.Background())
+	webhooks, err := api.NewWebhookManager(api.DataPath("webhooks.json"), playbackWatcher)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	router := gin.Default()
 	router.GET("/login", v1.UserLogin)
 	router.GET("/callback", v1.HandleCallback(tokenManager))
 	router.GET("/search", v1.SearchHandler(tokenManager))
+	router.GET("/browse/categories", v1.CategoriesHandler(tokenManager))
+	router.GET("/browse/categories/:id", v1.CategoryHandler(tokenManager))
+	router.GET("/browse/categories/:id/playlists", v1.CategoryPlaylistsHandler(tokenManager))
+	router.GET("/browse/featured-playlists", v1.FeaturedPlaylistsHandler(tokenManager))
+	router.GET("/browse/new-releases", v1.NewReleasesHandler(tokenManager))
+	router.GET("/markets", v1.MarketsHandler(tokenManager))
 	router.GET("/player", v1.PlayBackHandler(tokenManager))
-	router.PUT("/player", v1.PlayBackTransferHandler(tokenManager))
+	router.PUT("/player", v1.PlayBackTransferHandler(tokenManager, deviceResolver))
+	router.GET("/player/events", v1.PlaybackEventsHandler(tokenManager, playbackWatcher))
 	router.GET("/player/devices", v1.DevicesHandler(tokenManager))
+	router.GET("/player/devices/resolve", v1.ResolveDeviceHandler(tokenManager, deviceResolver))
+	router.GET("/player/devices/preferred", v1.GetPreferredDevicesHandler(tokenManager, preferredDevices))
+	router.PUT("/player/devices/preferred", v1.SetPreferredDevicesHandler(tokenManager, preferredDevices))
+	router.GET("/player/devices/aliases", v1.ListDeviceAliasesHandler(tokenManager, deviceResolver))
+	router.PUT("/player/devices/aliases/:alias", v1.SetDeviceAliasHandler(tokenManager, deviceResolver))
+	router.DELETE("/player/devices/aliases/:alias", v1.DeleteDeviceAliasHandler(tokenManager, deviceResolver))
 	router.GET("/player/currently-playing", v1.CurrentPlayingTrackHandler(tokenManager))
-	router.PUT("/player/play", v1.StartPlaybackHandler(tokenManager))
-	router.PUT("/player/pause", v1.PausePlaybackHandler(tokenManager))
-	router.PUT("/player/next", v1.SkipNextHandler(tokenManager))
-	router.PUT("/player/previous", v1.SkipPrevHandler(tokenManager))
-	router.PUT("/player/seek", v1.SeekPositionHandler(tokenManager))
-	router.PUT("/player/repeat", v1.ToggleRepeatHandler(tokenManager))
-	router.PUT("/player/volume", v1.SetPlaybackVolumeHandler(tokenManager))
-	router.PUT("/player/shuffle", v1.ToggleShuffleHandler(tokenManager))
+	router.PUT("/player/play", v1.StartPlaybackHandler(tokenManager, deviceResolver, preferredDevices))
+	router.PUT("/player/pause", v1.PausePlaybackHandler(tokenManager, deviceResolver))
+	router.PUT("/player/next", v1.SkipNextHandler(tokenManager, deviceResolver, preferredDevices))
+	router.PUT("/player/previous", v1.SkipPrevHandler(tokenManager, deviceResolver, preferredDevices))
+	router.PUT("/player/seek", v1.SeekPositionHandler(tokenManager, deviceResolver))
+	router.PUT("/player/repeat", v1.ToggleRepeatHandler(tokenManager, deviceResolver))
+	router.PUT("/player/volume", v1.SetPlaybackVolumeHandler(tokenManager, deviceResolver, volumeLimits, volumeFader))
+	router.GET("/player/volume/limits", v1.ListVolumeLimitsHandler(tokenManager, volumeLimits))
+	router.PUT("/player/volume/limits", v1.SetVolumeLimitHandler(tokenManager, deviceResolver, volumeLimits))
+	router.DELETE("/player/volume/limits/:device_id", v1.DeleteVolumeLimitHandler(tokenManager, volumeLimits))
+	router.PUT("/player/volume/fade", v1.VolumeFadeHandler(tokenManager, deviceResolver, volumeLimits, volumeFader))
+	router.PUT("/player/volume/fade-skip", v1.FadeSkipHandler(tokenManager, deviceResolver, volumeLimits, volumeFader))
+	router.PUT("/player/shuffle", v1.ToggleShuffleHandler(tokenManager, deviceResolver))
+	router.GET("/player/snapshots", v1.ListSnapshotsHandler(tokenManager, snapshots))
+	router.POST("/player/snapshots", v1.CreateSnapshotHandler(tokenManager, snapshots))
+	router.GET("/player/snapshots/:id", v1.GetSnapshotHandler(tokenManager, snapshots))
+	router.DELETE("/player/snapshots/:id", v1.DeleteSnapshotHandler(tokenManager, snapshots))
+	router.POST("/player/snapshots/:id/restore", v1.RestoreSnapshotHandler(tokenManager, snapshots, volumeLimits))
+	router.GET("/player/sleep", v1.ListSleepTimersHandler(tokenManager, sleepScheduler))
+	router.POST("/player/sleep", v1.CreateSleepTimerHandler(tokenManager, deviceResolver, sleepScheduler))
+	router.DELETE("/player/sleep/:id", v1.CancelSleepTimerHandler(tokenManager, sleepScheduler))
 	router.GET("/player/recently-played", v1.GetRecentlyPlayedHandler(tokenManager))
 	router.GET("/player/queue", v1.GetUsersQueueHandler(tokenManager))
-	router.POST("/player/queue", v1.AddToQueueHandler(tokenManager))
+	router.POST("/player/queue", v1.AddToQueueHandler(tokenManager, deviceResolver, preferredDevices))
+	router.POST("/player/queue/batch", v1.QueueBatchHandler(tokenManager, deviceResolver, preferredDevices))
+	router.GET("/player/managed-queue", v1.ManagedQueueHandler(tokenManager, managedQueues))
+	router.POST("/player/managed-queue", v1.InsertManagedQueueHandler(tokenManager, managedQueues))
+	router.DELETE("/player/managed-queue", v1.ClearManagedQueueHandler(tokenManager, managedQueues))
+	router.POST("/player/managed-queue/play", v1.PlayManagedQueueHandler(tokenManager, deviceResolver, managedQueues))
+	router.PUT("/player/managed-queue/:id/position", v1.MoveManagedQueueItemHandler(tokenManager, managedQueues))
+	router.DELETE("/player/managed-queue/:id", v1.RemoveManagedQueueItemHandler(tokenManager, managedQueues))
+	router.GET("/player/skip-rules", v1.GetSkipRulesHandler(tokenManager, skipRules))
+	router.PUT("/player/skip-rules", v1.SetSkipRulesHandler(tokenManager, skipRules))
+	router.GET("/player/skip-rules/audit", v1.SkipAuditHandler(tokenManager, skipRules))
+	router.GET("/ws", v1.WebSocketHandler(tokenManager, playbackWatcher, deviceResolver, preferredDevices, volumeLimits, volumeFader))
+	router.GET("/alarms", v1.ListAlarmsHandler(tokenManager, alarms))
+	router.POST("/alarms", v1.CreateAlarmHandler(tokenManager, alarms))
+	router.GET("/alarms/:id", v1.GetAlarmHandler(tokenManager, alarms))
+	router.PUT("/alarms/:id", v1.UpdateAlarmHandler(tokenManager, alarms))
+	router.DELETE("/alarms/:id", v1.DeleteAlarmHandler(tokenManager, alarms))
+	router.POST("/alarms/:id/run", v1.RunAlarmHandler(tokenManager, alarms))
+	router.GET("/macros", v1.ListMacrosHandler(tokenManager, macros))
+	router.GET("/macros/:name", v1.GetMacroHandler(tokenManager, macros))
+	router.PUT("/macros/:name", v1.SetMacroHandler(tokenManager, macros))
+	router.DELETE("/macros/:name", v1.DeleteMacroHandler(tokenManager, macros))
+	router.POST("/macros/:name/run", v1.RunMacroHandler(tokenManager, macros, volumeFader))
+	router.GET("/zones", v1.ListZonesHandler(tokenManager, zones))
+	router.GET("/zones/:name", v1.GetZoneHandler(tokenManager, zones))
+	router.PUT("/zones/:name", v1.SetZoneHandler(tokenManager, zones))
+	router.DELETE("/zones/:name", v1.DeleteZoneHandler(tokenManager, zones))
+	router.POST("/zones/:name/pause", v1.PauseZoneHandler(tokenManager, zones))
+	router.PUT("/zones/:name/volume", v1.SetZoneVolumeHandler(tokenManager, zones, volumeFader))
+	router.POST("/zones/:name/transfer", v1.TransferZoneHandler(tokenManager, zones))
+	router.GET("/party", v1.GetPartyHandler(tokenManager, parties))
+	router.POST("/party", v1.CreatePartyHandler(tokenManager, parties))
+	router.DELETE("/party", v1.ClosePartyHandler(tokenManager, parties))
+	router.PUT("/party/settings", v1.UpdatePartySettingsHandler(tokenManager, parties))
+	router.DELETE("/party/proposals/:id", v1.RemovePartyProposalHandler(tokenManager, parties))
+	router.POST("/party/bans", v1.BanPartyGuestHandler(tokenManager, parties))
+	router.POST("/party/rooms/:code/join", v1.JoinPartyHandler(parties))
+	router.GET("/party/rooms/:code", v1.GuestPartyHandler(parties))
+	router.GET("/party/rooms/:code/search", v1.PartySearchHandler(parties))
+	router.POST("/party/rooms/:code/proposals", v1.ProposePartyTrackHandler(parties))
+	router.PUT("/party/rooms/:code/proposals/:id/vote", v1.VotePartyTrackHandler(parties))
+	router.GET("/webhooks", v1.ListWebhooksHandler(tokenManager, webhooks))
+	router.POST("/webhooks", v1.CreateWebhookHandler(tokenManager, webhooks))
+	router.DELETE("/webhooks/:id", v1.DeleteWebhookHandler(tokenManager, webhooks))
+	router.GET("/webhooks/:id/deliveries", v1.WebhookDeliveriesHandler(tokenManager, webhooks))
+	router.POST("/webhooks/:id/test", v1.TestWebhookHandler(tokenManager, webhooks))
+	router.GET("/history", v1.HistoryHandler(tokenManager, historyStore))
+	router.GET("/stats", v1.StatsHandler(tokenManager, historyRecorder, historyStore))
 	router.Run("localhost:8080")
 }
 
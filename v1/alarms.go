@@ -0,0 +1,154 @@
+package v1
+
+import (
+	"net/http"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+// alarmRequest is the body accepted when creating or editing an alarm.
+// schedule is a five field cron expression or a descriptor such as "@daily", evaluated in timezone.
+type alarmRequest struct {
+	Name        string `json:"name" binding:"max=100"`
+	Schedule    string `json:"schedule" binding:"required"`
+	Timezone    string `json:"timezone"`
+	Enabled     *bool  `json:"enabled"`
+	DeviceID    string `json:"device_id"`
+	DeviceName  string `json:"device_name"`
+	ContextURI  string `json:"context_uri" binding:"required,spotify_uri=album artist playlist show"`
+	Volume      int    `json:"volume" binding:"gte=0,lte=100"`
+	Shuffle     bool   `json:"shuffle"`
+	RampFrom    int    `json:"ramp_from" binding:"gte=0,lte=100"`
+	RampSeconds int    `json:"ramp_seconds" binding:"gte=0,lte=3600"`
+}
+
+func (r alarmRequest) alarm(userID string) *api.Alarm {
+	enabled := r.Enabled == nil || *r.Enabled
+	return &api.Alarm{
+		UserID:      userID,
+		Name:        r.Name,
+		Schedule:    r.Schedule,
+		Timezone:    r.Timezone,
+		Enabled:     enabled,
+		DeviceID:    r.DeviceID,
+		DeviceName:  r.DeviceName,
+		ContextURI:  r.ContextURI,
+		Volume:      r.Volume,
+		Shuffle:     r.Shuffle,
+		RampFrom:    r.RampFrom,
+		RampSeconds: r.RampSeconds,
+	}
+}
+
+func CreateAlarmHandler(tokenMx *api.TokenManager, alarms *api.AlarmScheduler) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json alarmRequest
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		alarm := json.alarm(tokenMx.GetUser())
+		if status, err := alarms.Create(alarm); err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		created, _ := alarms.Get(alarm.UserID, alarm.ID)
+		ctx.JSON(http.StatusCreated, created)
+	}
+}
+
+func ListAlarmsHandler(tokenMx *api.TokenManager, alarms *api.AlarmScheduler) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"alarms": alarms.List(tokenMx.GetUser())})
+	}
+}
+
+func GetAlarmHandler(tokenMx *api.TokenManager, alarms *api.AlarmScheduler) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		alarm, ok := alarms.Get(tokenMx.GetUser(), ctx.Param("id"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Alarm not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, alarm)
+	}
+}
+
+func UpdateAlarmHandler(tokenMx *api.TokenManager, alarms *api.AlarmScheduler) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json alarmRequest
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		userID := tokenMx.GetUser()
+		found, status, err := alarms.Update(userID, ctx.Param("id"), json.alarm(userID))
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		if !found {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Alarm not found"})
+			return
+		}
+		updated, _ := alarms.Get(userID, ctx.Param("id"))
+		ctx.JSON(http.StatusOK, updated)
+	}
+}
+
+func DeleteAlarmHandler(tokenMx *api.TokenManager, alarms *api.AlarmScheduler) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		deleted, err := alarms.Delete(tokenMx.GetUser(), ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !deleted {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Alarm not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Alarm deleted"})
+	}
+}
+
+// RunAlarmHandler fires an alarm right away, which is handy for checking its settings.
+func RunAlarmHandler(tokenMx *api.TokenManager, alarms *api.AlarmScheduler) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		steps, ok := alarms.Run(tokenMx.GetUser(), ctx.Param("id"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Alarm not found"})
+			return
+		}
+		for _, step := range steps {
+			if step.Error != "" {
+				ctx.JSON(http.StatusBadGateway, gin.H{"status": "Alarm failed", "steps": steps})
+				return
+			}
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Alarm ran", "steps": steps})
+	}
+}
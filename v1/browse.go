@@ -0,0 +1,182 @@
+package v1
+
+import (
+	"iter"
+	"net/http"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+// localeOptions reads the locale and country query parameters shared by the browse handlers.
+func localeOptions(ctx *gin.Context) api.BrowseOptions {
+	return api.BrowseOptions{Locale: ctx.Query("locale"), Country: ctx.Query("country")}
+}
+
+// browseOptions reads the locale and country query parameters along with limit and offset for the browse lists.
+func browseOptions(ctx *gin.Context) (api.BrowseOptions, bool) {
+	opts := localeOptions(ctx)
+	var ok bool
+	opts.Limit, opts.Offset, ok = pagingParams(ctx)
+	return opts, ok
+}
+
+// maxBrowseItems caps how many items an all=true browse request collects.
+const maxBrowseItems = 1000
+
+// allBrowseItems answers an all=true browse request by walking every page from opts onwards,
+// fetching full pages when no limit was asked for.
+func allBrowseItems[T any](ctx *gin.Context, opts api.BrowseOptions, walk func(api.BrowseOptions) iter.Seq2[T, error]) {
+	if opts.Limit == 0 {
+		opts.Limit = 50
+	}
+	items := []T{}
+	for item, err := range walk(opts) {
+		if err != nil {
+			ctx.JSON(api.ErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		items = append(items, item)
+		if len(items) == maxBrowseItems {
+			break
+		}
+	}
+	ctx.JSON(http.StatusOK, gin.H{"items": items, "total": len(items)})
+}
+
+func CategoriesHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		opts, ok := browseOptions(ctx)
+		if !ok {
+			return
+		}
+		if ctx.Query("all") == "true" {
+			allBrowseItems(ctx, opts, func(opts api.BrowseOptions) iter.Seq2[api.Category, error] {
+				return api.AllCategories(accessToken, opts)
+			})
+			return
+		}
+		results, status, err := api.GetCategories(accessToken, opts)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, results)
+	}
+}
+
+func CategoryHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		result, status, err := api.GetCategory(accessToken, ctx.Param("id"), localeOptions(ctx))
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func CategoryPlaylistsHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		opts, ok := browseOptions(ctx)
+		if !ok {
+			return
+		}
+		if ctx.Query("all") == "true" {
+			allBrowseItems(ctx, opts, func(opts api.BrowseOptions) iter.Seq2[api.SimplifiedPlaylist, error] {
+				return api.AllCategoryPlaylists(accessToken, ctx.Param("id"), opts)
+			})
+			return
+		}
+		results, status, err := api.GetCategoryPlaylists(accessToken, ctx.Param("id"), opts)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, results)
+	}
+}
+
+func FeaturedPlaylistsHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		opts, ok := browseOptions(ctx)
+		if !ok {
+			return
+		}
+		if ctx.Query("all") == "true" {
+			allBrowseItems(ctx, opts, func(opts api.BrowseOptions) iter.Seq2[api.SimplifiedPlaylist, error] {
+				return api.AllFeaturedPlaylists(accessToken, opts)
+			})
+			return
+		}
+		results, status, err := api.GetFeaturedPlaylists(accessToken, opts)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, results)
+	}
+}
+
+func NewReleasesHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		opts, ok := browseOptions(ctx)
+		if !ok {
+			return
+		}
+		if ctx.Query("all") == "true" {
+			allBrowseItems(ctx, opts, func(opts api.BrowseOptions) iter.Seq2[api.SimplifiedAlbum, error] {
+				return api.AllNewReleases(accessToken, opts)
+			})
+			return
+		}
+		results, status, err := api.GetNewReleases(accessToken, opts)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, results)
+	}
+}
+
+func MarketsHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		results, status, err := api.GetAvailableMarkets(accessToken)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, results)
+	}
+}
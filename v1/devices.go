@@ -0,0 +1,125 @@
+package v1
+
+import (
+	"net/http"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resolveDevice turns the device_id or device_name of a request into the device ID sent to Spotify.
+// It writes the error response and returns false when no single device matches the name.
+func resolveDevice(ctx *gin.Context, devices *api.DeviceResolver, accessToken, userID, deviceID, deviceName string) (string, bool) {
+	resolved, status, err := devices.Resolve(accessToken, userID, deviceID, deviceName)
+	if err != nil {
+		ctx.JSON(status, gin.H{"error": err.Error()})
+		return "", false
+	}
+	return resolved, true
+}
+
+func ListDeviceAliasesHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"aliases": devices.Aliases(tokenMx.GetUser())})
+	}
+}
+
+// SetDeviceAliasHandler points the alias in the path at the device named in the JSON body.
+// The device does not need to be online; it is looked up each time the alias is used.
+func SetDeviceAliasHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			DeviceName string `json:"device_name" binding:"required,max=100"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		if status, err := devices.SetAlias(tokenMx.GetUser(), ctx.Param("alias"), json.DeviceName); err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Alias saved"})
+	}
+}
+
+func DeleteDeviceAliasHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		deleted, err := devices.DeleteAlias(tokenMx.GetUser(), ctx.Param("alias"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !deleted {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Alias not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Alias deleted"})
+	}
+}
+
+// ResolveDeviceHandler shows which device a name or alias resolves to, to check aliases and fuzzy matches.
+func ResolveDeviceHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		name := ctx.Query("name")
+		if name == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'name' is required"})
+			return
+		}
+		device, status, err := devices.Find(accessToken, tokenMx.GetUser(), name)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, device)
+	}
+}
+
+func GetPreferredDevicesHandler(tokenMx *api.TokenManager, preferred *api.PreferredDevices) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"devices": preferred.List(tokenMx.GetUser())})
+	}
+}
+
+// SetPreferredDevicesHandler replaces the ordered list of devices that playback is moved to when
+// a command fails because no device is active. Entries are device names, aliases or IDs.
+func SetPreferredDevicesHandler(tokenMx *api.TokenManager, preferred *api.PreferredDevices) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			Devices []string `json:"devices" binding:"max=20,dive,required,max=100"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		if status, err := preferred.Set(tokenMx.GetUser(), json.Devices); err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"devices": preferred.List(tokenMx.GetUser())})
+	}
+}
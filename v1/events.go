@@ -0,0 +1,50 @@
+package v1
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PlaybackEventsHandler streams playback changes as Server-Sent Events.
+// Each event is named after its type (track_changed, paused, ...) and carries the new playback state.
+// A state event with the last known playback state is sent first when one is available.
+func PlaybackEventsHandler(tokenMx *api.TokenManager, watcher *api.PlaybackWatcher) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		events, unsubscribe := watcher.Subscribe()
+		defer unsubscribe()
+
+		ctx.Header("Cache-Control", "no-cache")
+		ctx.Header("Connection", "keep-alive")
+		ctx.Header("X-Accel-Buffering", "no")
+		if current := watcher.Current(); current != nil {
+			ctx.SSEvent("state", current)
+		}
+
+		heartbeat := time.NewTicker(15 * time.Second)
+		defer heartbeat.Stop()
+		ctx.Stream(func(w io.Writer) bool {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return false
+				}
+				ctx.SSEvent(event.Type, event)
+				return true
+			case <-heartbeat.C:
+				_, err := io.WriteString(w, ": keep-alive\n\n")
+				return err == nil
+			case <-ctx.Request.Context().Done():
+				return false
+			}
+		})
+	}
+}
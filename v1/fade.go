@@ -0,0 +1,117 @@
+package v1
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VolumeFadeHandler starts moving the volume from its current level to volume over duration_ms along curve.
+// The target is capped to the device's volume limit. The fade runs in the background and is cancelled by
+// the next fade or volume command.
+func VolumeFadeHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver, limits *api.VolumeLimits, fader *api.VolumeFader) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			DeviceID   string `json:"device_id"`
+			DeviceName string `json:"device_name"`
+			Volume     int    `json:"volume" binding:"gte=0,lte=100"`
+			DurationMS int    `json:"duration_ms" binding:"gte=0,lte=600000"`
+			Curve      string `json:"curve" binding:"omitempty,oneof=linear ease-in ease-out ease-in-out exponential"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		curve, err := api.ParseFadeCurve(json.Curve)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		userID := tokenMx.GetUser()
+		deviceID, ok := resolveDevice(ctx, devices, accessToken, userID, json.DeviceID, json.DeviceName)
+		if !ok {
+			return
+		}
+		device, status, err := api.VolumeDevice(accessToken, deviceID)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		from := int(device.VolumePercent)
+		to := limits.Clamp(userID, device.ID, json.Volume)
+
+		fadeCtx, done := fader.Begin()
+		duration := time.Duration(json.DurationMS) * time.Millisecond
+		go func() {
+			defer done()
+			if _, err := api.FadeVolume(fadeCtx, accessToken, device.ID, from, to, duration, curve); err != nil && fadeCtx.Err() == nil {
+				log.Printf("volume fade: %v", err)
+			}
+		}()
+		ctx.JSON(http.StatusAccepted, gin.H{
+			"status":      "Volume fade started",
+			"from":        from,
+			"to":          to,
+			"limited":     to != json.Volume,
+			"duration_ms": json.DurationMS,
+			"curve":       curve,
+		})
+	}
+}
+
+// FadeSkipHandler fades out, skips to the next track and fades back in, each fade taking duration_ms.
+// Both ends of the fade are kept within the device's volume limit. Like a plain fade it runs in the
+// background and is cancelled by the next fade or volume command.
+func FadeSkipHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver, limits *api.VolumeLimits, fader *api.VolumeFader) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			DeviceID   string `json:"device_id"`
+			DeviceName string `json:"device_name"`
+			DurationMS int    `json:"duration_ms" binding:"gte=0,lte=60000"`
+			Curve      string `json:"curve" binding:"omitempty,oneof=linear ease-in ease-out ease-in-out exponential"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		if json.DurationMS == 0 {
+			json.DurationMS = 3000
+		}
+		curve, err := api.ParseFadeCurve(json.Curve)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		userID := tokenMx.GetUser()
+		deviceID, ok := resolveDevice(ctx, devices, accessToken, userID, json.DeviceID, json.DeviceName)
+		if !ok {
+			return
+		}
+		if _, status, err := api.DeviceVolume(accessToken, deviceID); err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		fadeCtx, done := fader.Begin()
+		duration := time.Duration(json.DurationMS) * time.Millisecond
+		go func() {
+			defer done()
+			if steps, ok := api.FadeSkip(fadeCtx, accessToken, userID, deviceID, limits, duration, curve); !ok && fadeCtx.Err() == nil {
+				log.Printf("fade skip: %+v", steps)
+			}
+		}()
+		ctx.JSON(http.StatusAccepted, gin.H{"status": "Fade skip started", "duration_ms": json.DurationMS, "curve": curve})
+	}
+}
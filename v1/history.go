@@ -0,0 +1,57 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HistoryHandler returns the plays recorded in the local history store for the logged-in user.
+// The optional from and to query parameters accept an RFC3339 timestamp or Unix milliseconds.
+func HistoryHandler(tokenMx *api.TokenManager, store *api.HistoryStore) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID := tokenMx.GetUser()
+		if userID == "" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "No user logged in"})
+			return
+		}
+		from, to, ok := timeRange(ctx)
+		if !ok {
+			return
+		}
+		limit, ok := queryInt(ctx, "limit", 1, 10000)
+		if !ok {
+			return
+		}
+
+		records := store.Range(userID, from, to)
+		if limit > 0 && len(records) > limit {
+			records = records[:limit]
+		}
+		ctx.JSON(http.StatusOK, gin.H{"items": records, "total": len(records)})
+	}
+}
+
+// timeRange reads the optional from and to query parameters.
+func timeRange(ctx *gin.Context) (from, to time.Time, ok bool) {
+	for name, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		raw := ctx.Query(name)
+		if raw == "" {
+			continue
+		}
+		value, err := parseTimestamp(raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": name + " must be an RFC3339 timestamp or Unix milliseconds"})
+			return from, to, false
+		}
+		*target = value
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return from, to, false
+	}
+	return from, to, true
+}
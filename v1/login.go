@@ -15,7 +15,7 @@ func UserLogin(ctx *gin.Context) {
 	params.Set("response_type", "code")
 	params.Set("redirect_uri", api.RedirectURI)
 	// user-modify-playback-state streaming
-	params.Set("scope", "user-read-email user-read-private user-read-playback-state user-modify-playback-state")
+	params.Set("scope", "user-read-email user-read-private user-read-playback-state user-modify-playback-state user-read-recently-played")
 	authURL := fmt.Sprintf("%s?%s", api.BaseAuthURL, params.Encode())
 	ctx.Redirect(http.StatusFound, authURL)
 }
@@ -42,6 +42,7 @@ func HandleCallback(tokenMx *api.TokenManager) gin.HandlerFunc{
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		tokenMx.SetUser(profile.ID)
 
 		ctx.JSON(http.StatusOK, profile)
 	}
@@ -0,0 +1,109 @@
+package v1
+
+import (
+	"net/http"
+	"slices"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ListMacrosHandler(tokenMx *api.TokenManager, macros *api.Macros) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"macros": macros.List(tokenMx.GetUser())})
+	}
+}
+
+func GetMacroHandler(tokenMx *api.TokenManager, macros *api.Macros) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		macro, ok := macros.Get(tokenMx.GetUser(), ctx.Param("name"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Macro not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, macro)
+	}
+}
+
+// SetMacroHandler creates or replaces the macro named in the path with the steps in the body.
+func SetMacroHandler(tokenMx *api.TokenManager, macros *api.Macros) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			Steps []api.MacroStep `json:"steps" binding:"required,min=1"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		macro, replaced, status, err := macros.Set(tokenMx.GetUser(), ctx.Param("name"), json.Steps)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		if replaced {
+			ctx.JSON(http.StatusOK, macro)
+			return
+		}
+		ctx.JSON(http.StatusCreated, macro)
+	}
+}
+
+func DeleteMacroHandler(tokenMx *api.TokenManager, macros *api.Macros) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		deleted, err := macros.Delete(tokenMx.GetUser(), ctx.Param("name"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !deleted {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Macro not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Macro deleted"})
+	}
+}
+
+// RunMacroHandler runs a macro and reports the result of every step.
+// Pass ?dry_run=true to resolve the devices and list the steps without touching playback.
+func RunMacroHandler(tokenMx *api.TokenManager, macros *api.Macros, fader *api.VolumeFader) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		userID := tokenMx.GetUser()
+		macro, ok := macros.Get(userID, ctx.Param("name"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Macro not found"})
+			return
+		}
+		dryRun := ctx.Query("dry_run") == "true"
+		// A running fade would undo the macro's volume steps.
+		if !dryRun && slices.ContainsFunc(macro.Steps, func(step api.MacroStep) bool { return step.Action == "volume" }) {
+			fader.Cancel()
+		}
+		steps, ok := macros.Run(ctx.Request.Context(), accessToken, userID, macro, dryRun)
+		if !ok {
+			ctx.JSON(http.StatusMultiStatus, gin.H{"status": "Macro failed", "dry_run": dryRun, "steps": steps})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Macro ran", "dry_run": dryRun, "steps": steps})
+	}
+}
@@ -0,0 +1,133 @@
+package v1
+
+import (
+	"net/http"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ManagedQueueHandler(tokenMx *api.TokenManager, queues *api.ManagedQueues) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"items": queues.List(tokenMx.GetUser())})
+	}
+}
+
+// InsertManagedQueueHandler adds track or episode URIs to the managed queue at position,
+// or at the end when position is left out.
+func InsertManagedQueueHandler(tokenMx *api.TokenManager, queues *api.ManagedQueues) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			URIs     []string `json:"uris" binding:"required,min=1,max=500,dive,spotify_uri=track episode"`
+			Position *int     `json:"position" binding:"omitempty,gte=0"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		position := -1
+		if json.Position != nil {
+			position = *json.Position
+		}
+		items, status, err := queues.Insert(tokenMx.GetUser(), json.URIs, position)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(status, gin.H{"items": items})
+	}
+}
+
+func MoveManagedQueueItemHandler(tokenMx *api.TokenManager, queues *api.ManagedQueues) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			Position int `json:"position" binding:"gte=0"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		found, err := queues.Move(tokenMx.GetUser(), ctx.Param("id"), json.Position)
+		if !found {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"items": queues.List(tokenMx.GetUser())})
+	}
+}
+
+func RemoveManagedQueueItemHandler(tokenMx *api.TokenManager, queues *api.ManagedQueues) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		removed, err := queues.Remove(tokenMx.GetUser(), ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !removed {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Item removed"})
+	}
+}
+
+func ClearManagedQueueHandler(tokenMx *api.TokenManager, queues *api.ManagedQueues) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		if err := queues.Clear(tokenMx.GetUser()); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Queue cleared"})
+	}
+}
+
+// PlayManagedQueueHandler starts the first item of the managed queue now; the rest follow as each item ends.
+func PlayManagedQueueHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver, queues *api.ManagedQueues) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			DeviceID   string `json:"device_id"`
+			DeviceName string `json:"device_name"`
+		}
+		if !bindOptionalJSON(ctx, &json) {
+			return
+		}
+		deviceID, ok := resolveDevice(ctx, devices, accessToken, tokenMx.GetUser(), json.DeviceID, json.DeviceName)
+		if !ok {
+			return
+		}
+		item, status, err := queues.Play(accessToken, tokenMx.GetUser(), deviceID)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Playback started", "item": item})
+	}
+}
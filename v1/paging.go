@@ -0,0 +1,73 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queryInt reads an optional integer query parameter bounded by min and max.
+// It writes a 400 response and returns false when the value is malformed or out of range.
+func queryInt(ctx *gin.Context, name string, min, max int) (int, bool) {
+	raw := ctx.Query(name)
+	if raw == "" {
+		return 0, true
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < min || value > max {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be a number between %d and %d", name, min, max)})
+		return 0, false
+	}
+	return value, true
+}
+
+// pagingParams reads the offset-based limit and offset query parameters.
+func pagingParams(ctx *gin.Context) (limit, offset int, ok bool) {
+	if limit, ok = queryInt(ctx, "limit", 1, 50); !ok {
+		return 0, 0, false
+	}
+	if offset, ok = queryInt(ctx, "offset", 0, 100000); !ok {
+		return 0, 0, false
+	}
+	return limit, offset, true
+}
+
+// cursorParams reads the cursor-based limit, after and before query parameters.
+// after and before accept either an RFC3339 timestamp or Unix milliseconds.
+func cursorParams(ctx *gin.Context) (api.RecentlyPlayedOptions, bool) {
+	var opts api.RecentlyPlayedOptions
+	limit, ok := queryInt(ctx, "limit", 1, 50)
+	if !ok {
+		return opts, false
+	}
+	opts.Limit = limit
+	for name, target := range map[string]*int64{"after": &opts.After, "before": &opts.Before} {
+		raw := ctx.Query(name)
+		if raw == "" {
+			continue
+		}
+		value, err := parseTimestamp(raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": name + " must be an RFC3339 timestamp or Unix milliseconds"})
+			return opts, false
+		}
+		*target = value.UnixMilli()
+	}
+	return opts, true
+}
+
+// parseTimestamp accepts an RFC3339 timestamp or a Unix timestamp in milliseconds.
+func parseTimestamp(raw string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		if ms < 0 {
+			return time.Time{}, fmt.Errorf("timestamp must not be negative")
+		}
+		return time.UnixMilli(ms), nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
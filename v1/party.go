@@ -0,0 +1,227 @@
+package v1
+
+import (
+	"net/http"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+// partyTokenHeader carries the token a guest got when joining a party.
+const partyTokenHeader = "X-Party-Token"
+
+// CreatePartyHandler opens a party room for the logged-in user and returns its code for guests to join.
+// max_per_guest caps how many tracks each guest can have waiting, 0 meaning no cap.
+func CreatePartyHandler(tokenMx *api.TokenManager, parties *api.PartyManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			MaxPerGuest int `json:"max_per_guest" binding:"gte=0,lte=100"`
+		}
+		if !bindOptionalJSON(ctx, &json) {
+			return
+		}
+		room, status, err := parties.Create(tokenMx.GetUser(), json.MaxPerGuest)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(status, room)
+	}
+}
+
+func GetPartyHandler(tokenMx *api.TokenManager, parties *api.PartyManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		room, ok := parties.Current(tokenMx.GetUser())
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "No party is open"})
+			return
+		}
+		ctx.JSON(http.StatusOK, room)
+	}
+}
+
+func ClosePartyHandler(tokenMx *api.TokenManager, parties *api.PartyManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		closed, err := parties.Close(tokenMx.GetUser())
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !closed {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "No party is open"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Party closed"})
+	}
+}
+
+func UpdatePartySettingsHandler(tokenMx *api.TokenManager, parties *api.PartyManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			MaxPerGuest int `json:"max_per_guest" binding:"gte=0,lte=100"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		status, err := parties.SetMaxPerGuest(tokenMx.GetUser(), json.MaxPerGuest)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Party updated"})
+	}
+}
+
+// RemovePartyProposalHandler lets the host take a track out of the party queue.
+func RemovePartyProposalHandler(tokenMx *api.TokenManager, parties *api.PartyManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		removed, err := parties.Remove(tokenMx.GetUser(), ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !removed {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Proposal not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Proposal removed"})
+	}
+}
+
+// BanPartyGuestHandler removes a guest's tracks and votes from the party and locks them out.
+// The party gets a new code, which the host shares with anyone who still has to join.
+func BanPartyGuestHandler(tokenMx *api.TokenManager, parties *api.PartyManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			GuestID string `json:"guest_id" binding:"required"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		code, banned, err := parties.Ban(tokenMx.GetUser(), json.GuestID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !banned {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Guest not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Guest banned", "code": code})
+	}
+}
+
+// JoinPartyHandler adds a guest to the party with the code in the path. Guests send the returned
+// token in the X-Party-Token header on every other party request.
+func JoinPartyHandler(parties *api.PartyManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var json struct {
+			Name string `json:"name" binding:"required,max=40"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		guest, token, status, err := parties.Join(ctx.Param("code"), json.Name)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(status, gin.H{"guest": guest, "token": token})
+	}
+}
+
+func GuestPartyHandler(parties *api.PartyManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		room, status, err := parties.View(ctx.Param("code"), ctx.GetHeader(partyTokenHeader))
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, room)
+	}
+}
+
+// PartySearchHandler searches tracks for a guest through the host's Spotify account.
+func PartySearchHandler(parties *api.PartyManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		query := ctx.Query("q")
+		if query == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
+			return
+		}
+		limit, offset, ok := pagingParams(ctx)
+		if !ok {
+			return
+		}
+		if limit == 0 {
+			limit = 10
+		}
+		results, status, err := parties.Search(ctx.Param("code"), ctx.GetHeader(partyTokenHeader), query, int32(limit), int32(offset))
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, results)
+	}
+}
+
+// ProposePartyTrackHandler adds a track URI to the party queue for the guest, counting as their upvote.
+func ProposePartyTrackHandler(parties *api.PartyManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var json struct {
+			URI string `json:"uri" binding:"required,spotify_uri=track"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		proposal, status, err := parties.Propose(ctx.Param("code"), ctx.GetHeader(partyTokenHeader), json.URI)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(status, proposal)
+	}
+}
+
+// VotePartyTrackHandler records the guest's vote on a proposal: 1 for up, -1 for down, 0 to withdraw.
+func VotePartyTrackHandler(parties *api.PartyManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var json struct {
+			Vote int `json:"vote" binding:"oneof=-1 0 1"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		proposal, status, err := parties.Vote(ctx.Param("code"), ctx.GetHeader(partyTokenHeader), ctx.Param("id"), json.Vote)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(status, proposal)
+	}
+}
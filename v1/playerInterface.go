@@ -3,6 +3,7 @@ package v1
 import (
 	api "blastboom/webservice/apis"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -30,17 +31,20 @@ func PlayBackHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 // PlayBackTransferHandler handles the transfer of playback to a different device by validating the token and calling the TransferPlayback API.
 // Parameters:
 // - tokenMx: a pointer to the TokenManager instance used to manage access tokens.
-func PlayBackTransferHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+func PlayBackTransferHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		accessToken, valid := tokenMx.GetToken()
 		if !valid {
 			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			return
 		}
-		deviceID := ctx.PostForm("device_id")
 		play := ctx.PostForm("play") == "true"
-		if deviceID == "" {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "device_id is required"})
+		if ctx.PostForm("device_id") == "" && ctx.PostForm("device_name") == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "device_id or device_name is required"})
+			return
+		}
+		deviceID, ok := resolveDevice(ctx, devices, accessToken, tokenMx.GetUser(), ctx.PostForm("device_id"), ctx.PostForm("device_name"))
+		if !ok {
 			return
 		}
 		status, err := api.TransferPlayback(accessToken, deviceID, play)
@@ -89,7 +93,7 @@ func CurrentPlayingTrackHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 	}
 }
 
-func StartPlaybackHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+func StartPlaybackHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver, preferred *api.PreferredDevices) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		accessToken, valid := tokenMx.GetToken()
 		if !valid {
@@ -98,35 +102,38 @@ func StartPlaybackHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 		}
 		var json struct {
 			DeviceID string `json:"device_id,omitempty"`
-			ContextURI string `json:"context_uri"`
-			Offset *struct {
-				Position int `json:"position,omitempty"`
-			} `json:"offset,omitempty"`
-			PositionMS int `json:"position_ms,omitempty"`
+			DeviceName string `json:"device_name,omitempty"`
+			api.PlaybackOptions
 		}
-		if err := ctx.ShouldBindJSON(&json); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		// An empty body is a plain resume.
+		if !bindOptionalJSON(ctx, &json) {
 			return
 		}
-		deviceID := json.DeviceID
-		contextURI := json.ContextURI
-		offsetPosition := 0
-		if json.Offset != nil {
-			offsetPosition = json.Offset.Position
+		if err := json.PlaybackOptions.Validate(); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		deviceID, ok := resolveDevice(ctx, devices, accessToken, tokenMx.GetUser(), json.DeviceID, json.DeviceName)
+		if !ok {
+			return
 		}
-		positionMS := json.PositionMS
 
-		statusCode, err := api.StartPlayback(
-			accessToken, deviceID, contextURI, offsetPosition, positionMS)
+		statusCode, err := preferred.WithFallback(accessToken, tokenMx.GetUser(), func() (int, error) {
+			return api.StartPlayback(accessToken, deviceID, json.PlaybackOptions)
+		})
 		if err != nil {
 			ctx.JSON(statusCode, gin.H{"error": err.Error()})
 			return
 		}
+		if json.PlaybackOptions.IsResume() {
+			ctx.JSON(statusCode, gin.H{"status": "Playback resumed"})
+			return
+		}
 		ctx.JSON(statusCode, gin.H{"status": "Playback started"})
 	}
 }
 
-func PausePlaybackHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+func PausePlaybackHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		accessToken, valid := tokenMx.GetToken()
 		if !valid {
@@ -135,13 +142,16 @@ func PausePlaybackHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 		}
 		var json struct {
 			DeviceID string `json:"device_id"`
+			DeviceName string `json:"device_name"`
 		}
-		if err := ctx.ShouldBindJSON(&json); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		if !bindJSON(ctx, &json) {
 			return
 		}
 
-		deviceID := json.DeviceID
+		deviceID, ok := resolveDevice(ctx, devices, accessToken, tokenMx.GetUser(), json.DeviceID, json.DeviceName)
+		if !ok {
+			return
+		}
 		statusCode, err := api.PausePlayback(accessToken, deviceID)
 		if err != nil {
 			ctx.JSON(statusCode, gin.H{"error": err.Error()})
@@ -150,7 +160,7 @@ func PausePlaybackHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 	}
 }
 
-func SkipNextHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+func SkipNextHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver, preferred *api.PreferredDevices) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		accessToken, valid := tokenMx.GetToken()
 		if !valid {
@@ -159,14 +169,19 @@ func SkipNextHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 		}
 		var json struct {
 			DeviceID string `json:"device_id"`
+			DeviceName string `json:"device_name"`
 		}
-		if err := ctx.ShouldBindJSON(&json); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		if !bindJSON(ctx, &json) {
 			return
 		}
 
-		deviceID := json.DeviceID
-		statusCode, err := api.SkipNext(accessToken, deviceID)
+		deviceID, ok := resolveDevice(ctx, devices, accessToken, tokenMx.GetUser(), json.DeviceID, json.DeviceName)
+		if !ok {
+			return
+		}
+		statusCode, err := preferred.WithFallback(accessToken, tokenMx.GetUser(), func() (int, error) {
+			return api.SkipNext(accessToken, deviceID)
+		})
 		if err != nil {
 			ctx.JSON(statusCode, gin.H{"error": err.Error()})
 			return
@@ -175,7 +190,7 @@ func SkipNextHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 	}
 }
 
-func SkipPrevHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+func SkipPrevHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver, preferred *api.PreferredDevices) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		accessToken, valid := tokenMx.GetToken()
 		if !valid {
@@ -184,14 +199,19 @@ func SkipPrevHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 		}
 		var json struct {
 			DeviceID string `json:"device_id"`
+			DeviceName string `json:"device_name"`
 		}
-		if err := ctx.ShouldBindJSON(&json); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		if !bindJSON(ctx, &json) {
 			return
 		}
 
-		deviceID := json.DeviceID
-		statusCode, err := api.SkipPrev(accessToken, deviceID)
+		deviceID, ok := resolveDevice(ctx, devices, accessToken, tokenMx.GetUser(), json.DeviceID, json.DeviceName)
+		if !ok {
+			return
+		}
+		statusCode, err := preferred.WithFallback(accessToken, tokenMx.GetUser(), func() (int, error) {
+			return api.SkipPrev(accessToken, deviceID)
+		})
 		if err != nil {
 			ctx.JSON(statusCode, gin.H{"error": err.Error()})
 			return
@@ -200,7 +220,9 @@ func SkipPrevHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 	}
 }
 
-func SeekPositionHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+// SeekPositionHandler seeks to an absolute position_ms, by a relative offset such as "+15s" or "-30s",
+// to a percent of the current track, or back to its start with restart. It answers with the new position.
+func SeekPositionHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		accessToken, valid := tokenMx.GetToken()
 		if !valid {
@@ -209,25 +231,63 @@ func SeekPositionHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 		}
 		var json struct {
 			DeviceID string `json:"device_id"`
-			PositionMS int `json:"position_ms"`
+			DeviceName string `json:"device_name"`
+			PositionMS *int `json:"position_ms" binding:"omitempty,gte=0"`
+			Offset string `json:"offset"`
+			Percent *float64 `json:"percent" binding:"omitempty,gte=0,lte=100"`
+			Restart bool `json:"restart"`
 		}
-		if err := ctx.ShouldBindJSON(&json); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		if !bindJSON(ctx, &json) {
 			return
 		}
+		given := 0
+		for _, set := range []bool{json.PositionMS != nil, json.Offset != "", json.Percent != nil, json.Restart} {
+			if set {
+				given++
+			}
+		}
+		if given != 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of position_ms, offset, percent or restart is required"})
+			return
+		}
+		var offset time.Duration
+		if json.Offset != "" {
+			var err error
+			if offset, err = api.ParseSeekOffset(json.Offset); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
 
-		deviceID := json.DeviceID
-		positionMS := json.PositionMS
-		statusCode, err := api.SeekPosition(accessToken, deviceID, positionMS)
+		deviceID, ok := resolveDevice(ctx, devices, accessToken, tokenMx.GetUser(), json.DeviceID, json.DeviceName)
+		if !ok {
+			return
+		}
+		var positionMS, statusCode int
+		var err error
+		switch {
+		case json.Offset != "":
+			positionMS, statusCode, err = api.SeekRelative(accessToken, deviceID, offset)
+		case json.Percent != nil:
+			positionMS, statusCode, err = api.SeekPercent(accessToken, deviceID, *json.Percent)
+		default:
+			if json.PositionMS != nil {
+				positionMS = *json.PositionMS
+			}
+			statusCode, err = api.SeekPosition(accessToken, deviceID, positionMS)
+		}
 		if err != nil {
 			ctx.JSON(statusCode, gin.H{"error": err.Error()})
 			return
 		}
-		ctx.JSON(statusCode, gin.H{"status": "Position seeked"})
+		if statusCode == http.StatusNoContent {
+			statusCode = http.StatusOK
+		}
+		ctx.JSON(statusCode, gin.H{"status": "Position seeked", "position_ms": positionMS})
 	}
 }
 
-func ToggleRepeatHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+func ToggleRepeatHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		accessToken, valid := tokenMx.GetToken()
 		if !valid {
@@ -236,25 +296,37 @@ func ToggleRepeatHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 		}
 		var json struct {
 			DeviceID string `json:"device_id"`
-			State    string `json:"state"` // "track", "context", or "off"
+			DeviceName string `json:"device_name"`
+			State    string `json:"state" binding:"omitempty,oneof=track context off"`
 		}
-		if err := ctx.ShouldBindJSON(&json); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		if !bindOptionalJSON(ctx, &json) {
 			return
 		}
 
-		deviceID := json.DeviceID
+		deviceID, ok := resolveDevice(ctx, devices, accessToken, tokenMx.GetUser(), json.DeviceID, json.DeviceName)
+		if !ok {
+			return
+		}
+		// Without a state, cycle off -> context -> track -> off from the current one.
 		state := json.State
-		statusCode, err := api.ToggleRepeat(accessToken, deviceID, state)
+		var statusCode int
+		var err error
+		if state == "" {
+			state, statusCode, err = api.CycleRepeat(accessToken, deviceID)
+		} else {
+			statusCode, err = api.ToggleRepeat(accessToken, deviceID, state)
+		}
 		if err != nil {
 			ctx.JSON(statusCode, gin.H{"error": err.Error()})
 			return
 		}
-		ctx.JSON(statusCode, gin.H{"status": "Repeat state toggled"})
+		ctx.JSON(http.StatusOK, gin.H{"status": "Repeat state toggled", "state": state})
 	}
 }
 
-func SetPlaybackVolumeHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+// SetPlaybackVolumeHandler sets an absolute volume, or with step moves it up or down from the device's
+// current volume. The volume is capped to the device's configured limits before it is sent to Spotify.
+func SetPlaybackVolumeHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver, limits *api.VolumeLimits, fader *api.VolumeFader) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		accessToken, valid := tokenMx.GetToken()
 		if !valid {
@@ -263,25 +335,39 @@ func SetPlaybackVolumeHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 		}
 		var json struct {
 			DeviceID string `json:"device_id"`
-			Volume   int    `json:"volume"`
+			DeviceName string `json:"device_name"`
+			Volume   *int    `json:"volume" binding:"omitempty,gte=0,lte=100"`
+			Step     *int    `json:"step" binding:"omitempty,gte=-100,lte=100"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
 		}
-		if err := ctx.ShouldBindJSON(&json); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		if (json.Volume != nil) == (json.Step != nil) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of volume or step is required"})
 			return
 		}
 
-		deviceID := json.DeviceID
-		volume := json.Volume
-		statusCode, err := api.SetPlaybackVolume(accessToken, deviceID, volume)
+		deviceID, ok := resolveDevice(ctx, devices, accessToken, tokenMx.GetUser(), json.DeviceID, json.DeviceName)
+		if !ok {
+			return
+		}
+		volume, relative := 0, json.Step != nil
+		if relative {
+			volume = *json.Step
+		} else {
+			volume = *json.Volume
+		}
+		fader.Cancel()
+		change, statusCode, err := limits.SetVolume(accessToken, tokenMx.GetUser(), deviceID, volume, relative)
 		if err != nil {
 			ctx.JSON(statusCode, gin.H{"error": err.Error()})
 			return
 		}
-		ctx.JSON(statusCode, gin.H{"status": "Playback volume set"})
+		ctx.JSON(http.StatusOK, gin.H{"status": "Playback volume set", "volume": change})
 	}
 }
 
-func ToggleShuffleHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+func ToggleShuffleHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		accessToken, valid := tokenMx.GetToken()
 		if !valid {
@@ -290,21 +376,31 @@ func ToggleShuffleHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 		}
 		var json struct {
 			DeviceID string `json:"device_id"`
-			State    bool   `json:"state"` // true for shuffle on, false for shuffle off
+			DeviceName string `json:"device_name"`
+			State    *bool  `json:"state"` // true for shuffle on, false for shuffle off, omitted to flip
 		}
-		if err := ctx.ShouldBindJSON(&json); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		if !bindOptionalJSON(ctx, &json) {
 			return
 		}
 
-		deviceID := json.DeviceID
-		state := json.State
-		statusCode, err := api.ToggleShuffle(accessToken, deviceID, state)
+		deviceID, ok := resolveDevice(ctx, devices, accessToken, tokenMx.GetUser(), json.DeviceID, json.DeviceName)
+		if !ok {
+			return
+		}
+		var state bool
+		var statusCode int
+		var err error
+		if json.State == nil {
+			state, statusCode, err = api.FlipShuffle(accessToken, deviceID)
+		} else {
+			state = *json.State
+			statusCode, err = api.ToggleShuffle(accessToken, deviceID, state)
+		}
 		if err != nil {
 			ctx.JSON(statusCode, gin.H{"error": err.Error()})
 			return
 		}
-		ctx.JSON(statusCode, gin.H{"status": "Shuffle state toggled"})
+		ctx.JSON(http.StatusOK, gin.H{"status": "Shuffle state toggled", "state": state})
 	}
 }
 
@@ -315,7 +411,31 @@ func GetRecentlyPlayedHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			return
 		}
-		results, status, err := api.GetRecentlyPlayed(accessToken)
+		opts, ok := cursorParams(ctx)
+		if !ok {
+			return
+		}
+		if ctx.Query("all") == "true" {
+			var after, before time.Time
+			if opts.After > 0 {
+				after = time.UnixMilli(opts.After)
+			}
+			if opts.Before > 0 {
+				before = time.UnixMilli(opts.Before)
+			}
+			items, status, err := api.RecentlyPlayedBetween(accessToken, after, before)
+			if err != nil {
+				ctx.JSON(status, gin.H{"error": err.Error()})
+				return
+			}
+			// Items come oldest first, so keep the newest limit plays of the window.
+			if opts.Limit > 0 && len(items) > opts.Limit {
+				items = items[len(items)-opts.Limit:]
+			}
+			ctx.JSON(http.StatusOK, gin.H{"items": items, "total": len(items)})
+			return
+		}
+		results, status, err := api.GetRecentlyPlayed(accessToken, opts)
 		if err != nil {
 			ctx.JSON(status, gin.H{"error": err.Error()})
 			return
@@ -340,7 +460,7 @@ func GetUsersQueueHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 	}
 }
 
-func AddToQueueHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
+func AddToQueueHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver, preferred *api.PreferredDevices) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		accessToken, valid := tokenMx.GetToken()
 		if !valid {
@@ -349,20 +469,26 @@ func AddToQueueHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 		}
 		var json struct {
 			DeviceID string `json:"device_id"`
-			URI      string `json:"uri"`
+			DeviceName string `json:"device_name"`
+			URI      string `json:"uri" binding:"required,spotify_uri=track episode"`
 		}
-		if err := ctx.ShouldBindJSON(&json); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		if !bindJSON(ctx, &json) {
 			return
 		}
 
-		deviceID := json.DeviceID
+		deviceID, ok := resolveDevice(ctx, devices, accessToken, tokenMx.GetUser(), json.DeviceID, json.DeviceName)
+		if !ok {
+			return
+		}
 		uri := json.URI
-		statusCode, err := api.AddToQueue(accessToken, deviceID, uri)
+		statusCode, err := preferred.WithFallback(accessToken, tokenMx.GetUser(), func() (int, error) {
+			return api.AddToQueue(accessToken, deviceID, uri)
+		})
 		if err != nil {
 			ctx.JSON(statusCode, gin.H{"error": err.Error()})
 			return
 		}
-		ctx.JSON(statusCode, gin.H{"status": "Added to queue"})
+		// Spotify answers 204 on success, which would drop the body.
+		ctx.JSON(http.StatusOK, gin.H{"status": "Added to queue"})
 	}
 }
@@ -0,0 +1,56 @@
+package v1
+
+import (
+	"net/http"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QueueBatchHandler queues an ordered list of track and episode URIs, expanding album and playlist URIs
+// into their tracks. With skip_queued set, items already in the queue or repeated in the batch are skipped.
+// It answers 207 with the per-item results when some items could not be queued.
+func QueueBatchHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver, preferred *api.PreferredDevices) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			DeviceID   string   `json:"device_id"`
+			DeviceName string   `json:"device_name"`
+			URIs       []string `json:"uris" binding:"required,min=1,max=50,dive,spotify_uri=track episode album playlist"`
+			SkipQueued bool     `json:"skip_queued"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		deviceID, ok := resolveDevice(ctx, devices, accessToken, tokenMx.GetUser(), json.DeviceID, json.DeviceName)
+		if !ok {
+			return
+		}
+		items, status, err := api.ExpandQueueURIs(accessToken, json.URIs)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		add := func(uri string) (int, error) {
+			return preferred.WithFallback(accessToken, tokenMx.GetUser(), func() (int, error) {
+				return api.AddToQueue(accessToken, deviceID, uri)
+			})
+		}
+		results, status, ok, err := api.QueueBatch(accessToken, items, json.SkipQueued, add)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			ctx.JSON(http.StatusMultiStatus, gin.H{"status": "Some items could not be queued", "items": results})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Added to queue", "items": results})
+	}
+}
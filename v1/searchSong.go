@@ -15,10 +15,16 @@ func SearchHandler(tokenMx *api.TokenManager) gin.HandlerFunc {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
 			return
 		}
+		limit, offset, ok := pagingParams(ctx)
+		if !ok {
+			return
+		}
+		if limit == 0 {
+			limit = 10
+		}
 		accToken, _ := tokenMx.GetToken()
-		
 
-		results, err := api.SearchSpotify(accToken, query, "track", 10)
+		results, err := api.SearchSpotify(accToken, query, "track", int32(limit), int32(offset))
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -0,0 +1,53 @@
+package v1
+
+import (
+	"net/http"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetSkipRulesHandler(tokenMx *api.TokenManager, skipRules *api.SkipRuleWatcher) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		ctx.JSON(http.StatusOK, skipRules.Rules(tokenMx.GetUser()))
+	}
+}
+
+// SetSkipRulesHandler replaces the user's skip rules; tracks that match them are skipped as soon as they play.
+func SetSkipRulesHandler(tokenMx *api.TokenManager, skipRules *api.SkipRuleWatcher) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var rules api.SkipRules
+		if !bindJSON(ctx, &rules) {
+			return
+		}
+		if status, err := skipRules.SetRules(tokenMx.GetUser(), rules); err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, skipRules.Rules(tokenMx.GetUser()))
+	}
+}
+
+// SkipAuditHandler lists the tracks skipped by the rules, newest first, with the rule each one broke.
+func SkipAuditHandler(tokenMx *api.TokenManager, skipRules *api.SkipRuleWatcher) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		limit, ok := queryInt(ctx, "limit", 1, 500)
+		if !ok {
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"skips": skipRules.Audit(tokenMx.GetUser(), limit)})
+	}
+}
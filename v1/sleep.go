@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSleepTimerHandler schedules a pause after a number of minutes or after a number of tracks,
+// where "tracks": 1 means at the end of the current track. fade_seconds fades the volume out first.
+func CreateSleepTimerHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver, scheduler *api.SleepScheduler) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			DeviceID    string  `json:"device_id"`
+			DeviceName  string  `json:"device_name"`
+			Minutes     float64 `json:"minutes" binding:"gte=0,lte=1440"`
+			Tracks      int     `json:"tracks" binding:"gte=0,lte=100"`
+			FadeSeconds int     `json:"fade_seconds" binding:"gte=0,lte=600"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		if (json.Minutes > 0) == (json.Tracks > 0) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of minutes or tracks is required"})
+			return
+		}
+		fade := time.Duration(json.FadeSeconds) * time.Second
+		deviceID, ok := resolveDevice(ctx, devices, accessToken, tokenMx.GetUser(), json.DeviceID, json.DeviceName)
+		if !ok {
+			return
+		}
+
+		if json.Minutes > 0 {
+			timer, err := scheduler.AfterDuration(tokenMx.GetUser(), deviceID, time.Duration(json.Minutes*float64(time.Minute)), fade)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusCreated, timer)
+			return
+		}
+		timer, status, err := scheduler.AfterTracks(accessToken, tokenMx.GetUser(), deviceID, json.Tracks, fade)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(status, timer)
+	}
+}
+
+func ListSleepTimersHandler(tokenMx *api.TokenManager, scheduler *api.SleepScheduler) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"timers": scheduler.List(tokenMx.GetUser())})
+	}
+}
+
+func CancelSleepTimerHandler(tokenMx *api.TokenManager, scheduler *api.SleepScheduler) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		cancelled, err := scheduler.Cancel(tokenMx.GetUser(), ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !cancelled {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Sleep timer not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Sleep timer cancelled"})
+	}
+}
@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"net/http"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSnapshotHandler captures the current playback state and queue and stores it for a later restore.
+// The optional JSON body may carry a "name" for the snapshot.
+func CreateSnapshotHandler(tokenMx *api.TokenManager, snapshots *api.SnapshotStore) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			Name string `json:"name" binding:"max=100"`
+		}
+		if !bindOptionalJSON(ctx, &json) {
+			return
+		}
+
+		snapshot, status, err := api.CaptureSnapshot(accessToken)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		snapshot.Name = json.Name
+		snapshot.UserID = tokenMx.GetUser()
+		if err := snapshots.Save(snapshot); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusCreated, snapshot)
+	}
+}
+
+func ListSnapshotsHandler(tokenMx *api.TokenManager, snapshots *api.SnapshotStore) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"snapshots": snapshots.List(tokenMx.GetUser())})
+	}
+}
+
+func GetSnapshotHandler(tokenMx *api.TokenManager, snapshots *api.SnapshotStore) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		snapshot, ok := snapshots.Get(tokenMx.GetUser(), ctx.Param("id"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, snapshot)
+	}
+}
+
+func DeleteSnapshotHandler(tokenMx *api.TokenManager, snapshots *api.SnapshotStore) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		deleted, err := snapshots.Delete(tokenMx.GetUser(), ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !deleted {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Snapshot deleted"})
+	}
+}
+
+// RestoreSnapshotHandler reapplies a stored snapshot and reports the result of every step.
+// Pass ?queue=true to also re-add the saved queue.
+func RestoreSnapshotHandler(tokenMx *api.TokenManager, snapshots *api.SnapshotStore, limits *api.VolumeLimits) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		snapshot, ok := snapshots.Get(tokenMx.GetUser(), ctx.Param("id"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+			return
+		}
+		steps, ok := api.RestoreSnapshot(accessToken, limits, snapshot, ctx.Query("queue") == "true")
+		if !ok {
+			ctx.JSON(http.StatusMultiStatus, gin.H{"status": "Snapshot partially restored", "steps": steps})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Snapshot restored", "steps": steps})
+	}
+}
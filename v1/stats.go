@@ -0,0 +1,68 @@
+package v1
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statsIngestWait bounds how long a stats request waits for the latest plays to be ingested.
+const statsIngestWait = 2 * time.Second
+
+// StatsHandler computes listening statistics for the logged-in user from the local history store.
+// The background recorder keeps the history; each request also ingests the plays newer than the latest
+// stored one, waiting at most statsIngestWait for Spotify before answering from the stored history.
+// Query parameters: from, to (RFC3339 or Unix milliseconds), top (size of each ranking) and tz (IANA time zone).
+func StatsHandler(tokenMx *api.TokenManager, recorder *api.HistoryRecorder, store *api.HistoryStore) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID := tokenMx.GetUser()
+		if userID == "" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "No user logged in"})
+			return
+		}
+		from, to, ok := timeRange(ctx)
+		if !ok {
+			return
+		}
+		top, ok := queryInt(ctx, "top", 1, 100)
+		if !ok {
+			return
+		}
+		if top == 0 {
+			top = 10
+		}
+		loc := time.UTC
+		if tz := ctx.Query("tz"); tz != "" {
+			var err error
+			if loc, err = time.LoadLocation(tz); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unknown time zone: " + tz})
+				return
+			}
+		}
+
+		ingested := make(chan struct{})
+		go func() {
+			defer close(ingested)
+			if _, err := recorder.PollLatest(); err != nil {
+				log.Printf("stats: using stored history only: %v", err)
+			}
+		}()
+		select {
+		case <-ingested:
+		case <-time.After(statsIngestWait):
+		}
+
+		stats := api.ComputeStats(store.Range(userID, from, to), top, loc, time.Now())
+		if !from.IsZero() {
+			stats.From = &from
+		}
+		if !to.IsZero() {
+			stats.To = &to
+		}
+		ctx.JSON(http.StatusOK, stats)
+	}
+}
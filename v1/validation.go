@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	// Report fields by their JSON names so errors match what the client sent.
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	// spotify_uri=track episode accepts Spotify URIs of the listed types, or of any type without a parameter.
+	validate.RegisterValidation("spotify_uri", func(fl validator.FieldLevel) bool {
+		uri := fl.Field().String()
+		if fl.Param() == "" {
+			_, _, err := api.ParseSpotifyURI(uri)
+			return err == nil
+		}
+		return api.ValidateSpotifyURI(uri, strings.Fields(fl.Param())...) == nil
+	})
+}
+
+// bindJSON decodes and validates the request body into obj. On failure it answers 400, listing a message
+// for each invalid field, and returns false.
+func bindJSON(ctx *gin.Context, obj interface{}) bool {
+	return checkBind(ctx, ctx.ShouldBindJSON(obj))
+}
+
+// bindOptionalJSON is bindJSON for endpoints whose body may be left out. A missing or empty body,
+// chunked or not, leaves obj untouched and returns true.
+func bindOptionalJSON(ctx *gin.Context, obj interface{}) bool {
+	if ctx.Request.Body == nil || ctx.Request.Body == http.NoBody {
+		return true
+	}
+	err := ctx.ShouldBindJSON(obj)
+	if errors.Is(err, io.EOF) {
+		return true
+	}
+	return checkBind(ctx, err)
+}
+
+// checkBind answers 400 for a failed bind and returns false, or returns true when err is nil.
+func checkBind(ctx *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	fields, ok := invalidFields(err)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return false
+	}
+	ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "fields": fields})
+	return false
+}
+
+// invalidFields maps each field that failed validation to its message. It reports false when err
+// is not a validation error.
+func invalidFields(err error) (map[string]string, bool) {
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return nil, false
+	}
+	fields := map[string]string{}
+	for _, fieldErr := range invalid {
+		fields[fieldName(fieldErr)] = fieldMessage(fieldErr)
+	}
+	return fields, true
+}
+
+// fieldName returns the JSON path of the field, without the name of the top-level struct.
+func fieldName(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return fieldErr.Field()
+}
+
+func fieldMessage(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if unit := lengthUnit(fieldErr.Kind()); unit != "" {
+			return fmt.Sprintf("must have at least %s %s", param, unit)
+		}
+		return "must be at least " + param
+	case "max":
+		if unit := lengthUnit(fieldErr.Kind()); unit != "" {
+			return fmt.Sprintf("must have at most %s %s", param, unit)
+		}
+		return "must be at most " + param
+	case "gte":
+		return "must be at least " + param
+	case "lte":
+		return "must be at most " + param
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "url":
+		return "must be an absolute URL"
+	case "spotify_uri":
+		if param == "" {
+			return "must be a Spotify URI"
+		}
+		return "must be a Spotify " + strings.Join(strings.Fields(param), " or ") + " URI"
+	}
+	return "is invalid (" + fieldErr.Tag() + ")"
+}
+
+// lengthUnit names what min and max count for slices and strings, and is empty for numbers.
+func lengthUnit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return "items"
+	case reflect.String:
+		return "characters"
+	}
+	return ""
+}
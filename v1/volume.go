@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"net/http"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ListVolumeLimitsHandler(tokenMx *api.TokenManager, limits *api.VolumeLimits) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"limits": limits.List(tokenMx.GetUser())})
+	}
+}
+
+// SetVolumeLimitHandler caps the volume of a device, given by device_id or device_name, between min and max.
+func SetVolumeLimitHandler(tokenMx *api.TokenManager, devices *api.DeviceResolver, limits *api.VolumeLimits) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			DeviceID   string `json:"device_id"`
+			DeviceName string `json:"device_name"`
+			Min        int    `json:"min" binding:"gte=0,lte=100"`
+			Max        int    `json:"max" binding:"gte=0,lte=100"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		limit := api.VolumeLimit{DeviceID: json.DeviceID, Min: json.Min, Max: json.Max}
+		if json.DeviceID == "" && json.DeviceName != "" {
+			device, status, err := devices.Find(accessToken, tokenMx.GetUser(), json.DeviceName)
+			if err != nil {
+				ctx.JSON(status, gin.H{"error": err.Error()})
+				return
+			}
+			limit.DeviceID, limit.DeviceName = device.ID, device.Name
+		}
+		if status, err := limits.Set(tokenMx.GetUser(), limit); err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, limit)
+	}
+}
+
+func DeleteVolumeLimitHandler(tokenMx *api.TokenManager, limits *api.VolumeLimits) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		deleted, err := limits.Delete(tokenMx.GetUser(), ctx.Param("device_id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !deleted {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Volume limit not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Volume limit removed"})
+	}
+}
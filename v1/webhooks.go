@@ -0,0 +1,97 @@
+package v1
+
+import (
+	"net/http"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+func CreateWebhookHandler(tokenMx *api.TokenManager, webhooks *api.WebhookManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			URL    string   `json:"url" binding:"required,url"`
+			Secret string   `json:"secret"`
+			Events []string `json:"events" binding:"dive,oneof=track_changed device_changed paused resumed"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		hook, status, err := webhooks.Add(json.URL, json.Secret, json.Events)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(status, hook)
+	}
+}
+
+func ListWebhooksHandler(tokenMx *api.TokenManager, webhooks *api.WebhookManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"webhooks": webhooks.List()})
+	}
+}
+
+func DeleteWebhookHandler(tokenMx *api.TokenManager, webhooks *api.WebhookManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		removed, err := webhooks.Remove(ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !removed {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Webhook deleted"})
+	}
+}
+
+func WebhookDeliveriesHandler(tokenMx *api.TokenManager, webhooks *api.WebhookManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		deliveries, ok := webhooks.Deliveries(ctx.Param("id"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+	}
+}
+
+// TestWebhookHandler sends a signed test event to the webhook and returns the delivery result,
+// so a receiver can be checked without waiting for a playback change.
+func TestWebhookHandler(tokenMx *api.TokenManager, webhooks *api.WebhookManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		delivery, ok := webhooks.Test(ctx.Param("id"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return
+		}
+		status := http.StatusOK
+		if delivery.Error != "" {
+			status = http.StatusBadGateway
+		}
+		ctx.JSON(status, delivery)
+	}
+}
@@ -0,0 +1,302 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait  = 5 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     checkOrigin,
+}
+
+// checkOrigin accepts non-browser clients, same-origin pages and the origins listed in BLASTBOOM_WS_ORIGINS.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	if err == nil && strings.EqualFold(parsed.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range strings.Split(api.WSAllowedOrigins, ",") {
+		if allowed = strings.TrimSpace(allowed); allowed != "" && strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// playerCommand is a remote-control message sent by a WebSocket client.
+// State is a string for repeat ("track", "context" or "off") and a boolean for shuffle. Without a state,
+// repeat moves on to the next state and shuffle is flipped.
+// Offset is the position to start at in the context or uris, OffsetURI the item to start at instead.
+type playerCommand struct {
+	ID         string          `json:"id,omitempty"`
+	Command    string          `json:"command" binding:"required,oneof=play pause next previous seek volume shuffle repeat queue transfer"`
+	DeviceID   string          `json:"device_id,omitempty"`
+	DeviceName string          `json:"device_name,omitempty"`
+	ContextURI string          `json:"context_uri,omitempty" binding:"omitempty,spotify_uri=album artist playlist show"`
+	URIs       []string        `json:"uris,omitempty" binding:"max=100,dive,spotify_uri=track episode"`
+	Offset     int             `json:"offset,omitempty" binding:"gte=0"`
+	OffsetURI  string          `json:"offset_uri,omitempty" binding:"omitempty,spotify_uri=track episode"`
+	PositionMS *int            `json:"position_ms,omitempty" binding:"omitempty,gte=0"`
+	Volume     *int            `json:"volume,omitempty" binding:"omitempty,gte=0,lte=100"`
+	Step       *int            `json:"step,omitempty" binding:"omitempty,gte=-100,lte=100"`
+	State      json.RawMessage `json:"state,omitempty"`
+	URI        string          `json:"uri,omitempty" binding:"omitempty,spotify_uri=track episode"`
+}
+
+// validate checks cmd with the same rules and messages as request bodies, plus the fields each command needs.
+// It returns nil when the command is valid.
+func (cmd *playerCommand) validate() map[string]string {
+	if err := binding.Validator.ValidateStruct(cmd); err != nil {
+		if fields, ok := invalidFields(err); ok {
+			return fields
+		}
+		return map[string]string{"command": err.Error()}
+	}
+	switch cmd.Command {
+	case "seek":
+		if cmd.PositionMS == nil {
+			return map[string]string{"position_ms": "is required"}
+		}
+	case "volume":
+		if (cmd.Volume == nil) == (cmd.Step == nil) {
+			return map[string]string{"volume": "exactly one of volume or step is required"}
+		}
+	case "queue":
+		if cmd.URI == "" {
+			return map[string]string{"uri": "is required"}
+		}
+	case "transfer":
+		if cmd.DeviceID == "" && cmd.DeviceName == "" {
+			return map[string]string{"device_id": "is required unless device_name is given"}
+		}
+	case "shuffle":
+		var state bool
+		if len(cmd.State) > 0 && json.Unmarshal(cmd.State, &state) != nil {
+			return map[string]string{"state": "must be true or false"}
+		}
+	case "repeat":
+		var state string
+		if len(cmd.State) > 0 && (json.Unmarshal(cmd.State, &state) != nil || !slices.Contains([]string{"track", "context", "off"}, state)) {
+			return map[string]string{"state": "must be one of track, context, off"}
+		}
+	}
+	return nil
+}
+
+// wsMessage is every message the server sends over the socket.
+type wsMessage struct {
+	Type    string                `json:"type"`
+	ID      string                `json:"id,omitempty"`
+	Command string                `json:"command,omitempty"`
+	Status  int                   `json:"status,omitempty"`
+	Error   string                `json:"error,omitempty"`
+	Fields  map[string]string     `json:"fields,omitempty"`
+	Event   *api.PlaybackEvent    `json:"event,omitempty"`
+	State   *api.PlayBackResponse `json:"state,omitempty"`
+}
+
+// runCommand maps a validated command onto the matching player API call. cmd.DeviceName has already been
+// resolved into cmd.DeviceID.
+func runCommand(accessToken, userID string, limits *api.VolumeLimits, fader *api.VolumeFader, cmd playerCommand) (int, error) {
+	switch cmd.Command {
+	case "play":
+		opts := api.PlaybackOptions{ContextURI: cmd.ContextURI, URIs: cmd.URIs}
+		if cmd.PositionMS != nil {
+			opts.PositionMS = *cmd.PositionMS
+		}
+		if cmd.OffsetURI != "" {
+			opts.Offset = &api.PlaybackOffset{URI: cmd.OffsetURI}
+		} else if cmd.Offset > 0 {
+			opts.Offset = &api.PlaybackOffset{Position: &cmd.Offset}
+		}
+		if err := opts.Validate(); err != nil {
+			return http.StatusBadRequest, err
+		}
+		return api.StartPlayback(accessToken, cmd.DeviceID, opts)
+	case "pause":
+		return api.PausePlayback(accessToken, cmd.DeviceID)
+	case "next":
+		return api.SkipNext(accessToken, cmd.DeviceID)
+	case "previous":
+		return api.SkipPrev(accessToken, cmd.DeviceID)
+	case "seek":
+		return api.SeekPosition(accessToken, cmd.DeviceID, *cmd.PositionMS)
+	case "volume":
+		fader.Cancel()
+		volume := cmd.Step
+		if cmd.Volume != nil {
+			volume = cmd.Volume
+		}
+		_, status, err := limits.SetVolume(accessToken, userID, cmd.DeviceID, *volume, cmd.Volume == nil)
+		return status, err
+	case "shuffle":
+		if len(cmd.State) == 0 {
+			_, status, err := api.FlipShuffle(accessToken, cmd.DeviceID)
+			return status, err
+		}
+		var state bool
+		if err := json.Unmarshal(cmd.State, &state); err != nil {
+			return http.StatusBadRequest, fmt.Errorf("shuffle state must be true or false")
+		}
+		return api.ToggleShuffle(accessToken, cmd.DeviceID, state)
+	case "repeat":
+		if len(cmd.State) == 0 {
+			_, status, err := api.CycleRepeat(accessToken, cmd.DeviceID)
+			return status, err
+		}
+		var state string
+		if err := json.Unmarshal(cmd.State, &state); err != nil {
+			return http.StatusBadRequest, fmt.Errorf("repeat state must be track, context or off")
+		}
+		return api.ToggleRepeat(accessToken, cmd.DeviceID, state)
+	case "queue":
+		return api.AddToQueue(accessToken, cmd.DeviceID, cmd.URI)
+	case "transfer":
+		if cmd.DeviceID == "" {
+			return http.StatusBadRequest, fmt.Errorf("device_id or device_name is required")
+		}
+		return api.TransferPlayback(accessToken, cmd.DeviceID, true)
+	}
+	return http.StatusBadRequest, fmt.Errorf("unknown command %q", cmd.Command)
+}
+
+// wsConn serialises writes to a WebSocket connection, which allows only one writer at a time.
+type wsConn struct {
+	conn *websocket.Conn
+	mutx sync.Mutex
+}
+
+func (c *wsConn) send(msg wsMessage) error {
+	c.mutx.Lock()
+	defer c.mutx.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return c.conn.WriteJSON(msg)
+}
+
+func (c *wsConn) ping() error {
+	c.mutx.Lock()
+	defer c.mutx.Unlock()
+
+	return c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+}
+
+// WebSocketHandler opens a two-way remote-control channel. The server pushes playback events as they
+// are detected and answers each command with a result message followed by the fresh playback state.
+func WebSocketHandler(tokenMx *api.TokenManager, watcher *api.PlaybackWatcher, devices *api.DeviceResolver, preferred *api.PreferredDevices, limits *api.VolumeLimits, fader *api.VolumeFader) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+		if err != nil {
+			log.Printf("ws: upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		client := &wsConn{conn: conn}
+
+		events, unsubscribe := watcher.Subscribe()
+		defer unsubscribe()
+		done := make(chan struct{})
+		defer close(done)
+
+		if current := watcher.Current(); current != nil {
+			client.send(wsMessage{Type: "state", State: current})
+		}
+
+		go func() {
+			ticker := time.NewTicker(wsPingPeriod)
+			defer ticker.Stop()
+			for {
+				select {
+				case event, ok := <-events:
+					if !ok {
+						return
+					}
+					if err := client.send(wsMessage{Type: "event", Event: &event}); err != nil {
+						return
+					}
+				case <-ticker.C:
+					if err := client.ping(); err != nil {
+						return
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+		for {
+			var cmd playerCommand
+			if err := conn.ReadJSON(&cmd); err != nil {
+				var syntaxErr *json.SyntaxError
+				var typeErr *json.UnmarshalTypeError
+				if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+					client.send(wsMessage{Type: "result", Status: http.StatusBadRequest, Error: "Invalid JSON"})
+					continue
+				}
+				return
+			}
+
+			if fields := cmd.validate(); fields != nil {
+				client.send(wsMessage{Type: "result", ID: cmd.ID, Command: cmd.Command, Status: http.StatusBadRequest, Error: "Invalid request", Fields: fields})
+				continue
+			}
+			accessToken, valid := tokenMx.GetToken()
+			if !valid {
+				client.send(wsMessage{Type: "result", ID: cmd.ID, Command: cmd.Command, Status: http.StatusUnauthorized, Error: "Invalid token"})
+				continue
+			}
+			result := wsMessage{Type: "result", ID: cmd.ID, Command: cmd.Command}
+			cmd.DeviceID, result.Status, err = devices.Resolve(accessToken, tokenMx.GetUser(), cmd.DeviceID, cmd.DeviceName)
+			if err == nil {
+				result.Status, err = preferred.WithFallback(accessToken, tokenMx.GetUser(), func() (int, error) {
+					return runCommand(accessToken, tokenMx.GetUser(), limits, fader, cmd)
+				})
+			}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			if err := client.send(result); err != nil {
+				return
+			}
+			if result.Error == "" {
+				if state, _, err := api.GetPlayBack(accessToken); err == nil {
+					client.send(wsMessage{Type: "state", ID: cmd.ID, State: state})
+				}
+			}
+		}
+	}
+}
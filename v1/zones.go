@@ -0,0 +1,171 @@
+package v1
+
+import (
+	"net/http"
+
+	api "blastboom/webservice/apis"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ListZonesHandler(tokenMx *api.TokenManager, zones *api.Zones) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"zones": zones.List(tokenMx.GetUser())})
+	}
+}
+
+func GetZoneHandler(tokenMx *api.TokenManager, zones *api.Zones) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		zone, ok := zones.Get(tokenMx.GetUser(), ctx.Param("name"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Zone not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, zone)
+	}
+}
+
+// SetZoneHandler creates or replaces the zone named in the path with the devices in the body,
+// given by ID in device_ids or by name or alias in device_names.
+func SetZoneHandler(tokenMx *api.TokenManager, zones *api.Zones) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			DeviceIDs   []string `json:"device_ids" binding:"max=20,dive,required"`
+			DeviceNames []string `json:"device_names" binding:"max=20,dive,required,max=100"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		zone, replaced, status, err := zones.Set(accessToken, tokenMx.GetUser(), ctx.Param("name"), json.DeviceIDs, json.DeviceNames)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		if replaced {
+			ctx.JSON(http.StatusOK, zone)
+			return
+		}
+		ctx.JSON(http.StatusCreated, zone)
+	}
+}
+
+func DeleteZoneHandler(tokenMx *api.TokenManager, zones *api.Zones) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, valid := tokenMx.GetToken(); !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		deleted, err := zones.Delete(tokenMx.GetUser(), ctx.Param("name"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !deleted {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Zone not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "Zone deleted"})
+	}
+}
+
+// PauseZoneHandler pauses whichever of the zone's devices is playing.
+func PauseZoneHandler(tokenMx *api.TokenManager, zones *api.Zones) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		zone, ok := zones.Get(tokenMx.GetUser(), ctx.Param("name"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Zone not found"})
+			return
+		}
+		results, status, ok, err := zones.Pause(accessToken, zone)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		zoneResponse(ctx, "Zone paused", results, ok)
+	}
+}
+
+// SetZoneVolumeHandler sets every device in the zone to the same volume, within each device's limits.
+func SetZoneVolumeHandler(tokenMx *api.TokenManager, zones *api.Zones, fader *api.VolumeFader) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			Volume *int `json:"volume" binding:"required,gte=0,lte=100"`
+		}
+		if !bindJSON(ctx, &json) {
+			return
+		}
+		userID := tokenMx.GetUser()
+		zone, ok := zones.Get(userID, ctx.Param("name"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Zone not found"})
+			return
+		}
+		fader.Cancel()
+		results, status, ok, err := zones.SetVolume(accessToken, userID, zone, *json.Volume)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		zoneResponse(ctx, "Zone volume set", results, ok)
+	}
+}
+
+// TransferZoneHandler moves playback to the zone's first available device. The optional JSON body may set "play".
+func TransferZoneHandler(tokenMx *api.TokenManager, zones *api.Zones) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken, valid := tokenMx.GetToken()
+		if !valid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		var json struct {
+			Play bool `json:"play"`
+		}
+		if !bindOptionalJSON(ctx, &json) {
+			return
+		}
+		zone, ok := zones.Get(tokenMx.GetUser(), ctx.Param("name"))
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Zone not found"})
+			return
+		}
+		results, status, ok, err := zones.Transfer(accessToken, zone, json.Play)
+		if err != nil {
+			ctx.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		zoneResponse(ctx, "Zone transferred", results, ok)
+	}
+}
+
+// zoneResponse answers 200 when every device succeeded and 207 with the per-device results otherwise.
+func zoneResponse(ctx *gin.Context, status string, results []api.ZoneResult, ok bool) {
+	if !ok {
+		ctx.JSON(http.StatusMultiStatus, gin.H{"status": "Some devices failed", "devices": results})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": status, "devices": results})
+}